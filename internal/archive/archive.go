@@ -0,0 +1,677 @@
+// Package archive packs the raw storage files of one or more sessions into a
+// single portable .tar.zst or .zip file and unpacks them on another machine.
+// Sessions kept in opencode's database are packed as the files they would be
+// stored in.
+//
+// Files are stored under layout-neutral names (sessions/<id>/... and
+// snapshot/<project>/...) and a manifest lists each one with its checksum,
+// so an archive made from the legacy project layout can be restored into the
+// hash-based layout and the other way around.
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// ManifestName is the archive entry holding the manifest. It is always the
+// first entry so streaming formats can read it before any file content.
+const ManifestName = "manifest.json"
+
+// ManifestFormat identifies archives produced by this package
+const ManifestFormat = "opencode-session-export/archive"
+
+// ManifestVersion is incremented on incompatible manifest changes. Version 2
+// files snapshot data under the project it was packed from.
+const ManifestVersion = 2
+
+// FileSnapshot marks files from opencode's snapshot repository
+const FileSnapshot session.FileKind = "snapshot"
+
+// ErrSessionExists is returned by Unpack when a session in the archive is
+// already present in the target storage
+var ErrSessionExists = errors.New("session already exists")
+
+// ErrMessageExists is returned by Unpack when another session in the target
+// storage already uses one of the archive's message IDs. Overwriting does not
+// help, as it only replaces the archive's own sessions.
+var ErrMessageExists = errors.New("message ID already in use")
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Format       string            `json:"format"`
+	Version      int               `json:"version"`
+	CreatedAt    time.Time         `json:"createdAt"`
+	SourceLayout session.Layout    `json:"sourceLayout"`
+	Sessions     []ManifestSession `json:"sessions"`
+	Files        []ManifestFile    `json:"files"`
+}
+
+// ManifestSession lists a session contained in the archive
+type ManifestSession struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ManifestFile describes a single archived file
+type ManifestFile struct {
+	Path      string           `json:"path"`
+	Kind      session.FileKind `json:"kind"`
+	SessionID string           `json:"sessionID,omitempty"`
+	MessageID string           `json:"messageID,omitempty"`
+	PartID    string           `json:"partID,omitempty"`
+	Project   string           `json:"project,omitempty"`
+	Size      int64            `json:"size"`
+	SHA256    string           `json:"sha256"`
+}
+
+// PackOptions controls what Pack includes
+type PackOptions struct {
+	// SkipSnapshots leaves out the project's snapshot repository
+	SkipSnapshots bool
+}
+
+// UnpackOptions controls how Unpack treats existing data
+type UnpackOptions struct {
+	// Overwrite replaces sessions that already exist in the target storage
+	Overwrite bool
+}
+
+type packEntry struct {
+	file ManifestFile
+	// source is the file the entry is read from, unless data holds its
+	// content, as for records read from a database
+	source  string
+	data    []byte
+	modTime time.Time
+}
+
+// Pack writes the given sessions, read through reader, into w
+func Pack(reader *session.Reader, sessionIDs []string, w io.Writer, format Format, opts PackOptions) (*Manifest, error) {
+	manifest := &Manifest{
+		Format:       ManifestFormat,
+		Version:      ManifestVersion,
+		CreatedAt:    time.Now().UTC(),
+		SourceLayout: reader.Layout(),
+	}
+
+	var entries []packEntry
+	// Snapshot directories already collected, by the project they are
+	// archived under
+	snapshotProjects := make(map[string]string)
+
+	for _, sessionID := range sessionIDs {
+		info, err := reader.ReadSessionInfo(sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", sessionID, err)
+		}
+		manifest.Sessions = append(manifest.Sessions, ManifestSession{ID: info.ID, Title: info.Title})
+
+		files, err := reader.SessionFiles(sessionID)
+		if errors.Is(err, session.ErrNotFileStorage) {
+			recordEntries, err := collectRecords(reader, info)
+			if err != nil {
+				return nil, fmt.Errorf("session %s: %w", sessionID, err)
+			}
+			entries = append(entries, recordEntries...)
+		} else if err != nil {
+			return nil, fmt.Errorf("session %s: %w", sessionID, err)
+		}
+
+		for _, file := range files {
+			entries = append(entries, packEntry{
+				file:   sessionManifestFile(file),
+				source: file.Path,
+			})
+		}
+
+		if opts.SkipSnapshots {
+			continue
+		}
+
+		snapshotDir, err := reader.SnapshotDir(sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", sessionID, err)
+		}
+		if snapshotDir == "" || snapshotProjects[snapshotDir] != "" {
+			continue
+		}
+		project := snapshotProject(snapshotDir, snapshotProjects)
+		snapshotProjects[snapshotDir] = project
+
+		snapshotEntries, err := collectSnapshot(snapshotDir, project)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, snapshotEntries...)
+	}
+
+	// Checksums go into the manifest, which is written before any file, so
+	// every file is hashed up front
+	for i := range entries {
+		if entries[i].data != nil {
+			sum := sha256.Sum256(entries[i].data)
+			entries[i].file.Size = int64(len(entries[i].data))
+			entries[i].file.SHA256 = hex.EncodeToString(sum[:])
+			entries[i].modTime = manifest.CreatedAt
+		} else {
+			size, sum, modTime, err := hashFile(entries[i].source)
+			if err != nil {
+				return nil, err
+			}
+			entries[i].file.Size = size
+			entries[i].file.SHA256 = sum
+			entries[i].modTime = modTime
+		}
+		manifest.Files = append(manifest.Files, entries[i].file)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	ew, err := newEntryWriter(format, w)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ew.WriteEntry(ManifestName, int64(len(manifestData)), manifest.CreatedAt, bytes.NewReader(manifestData)); err != nil {
+		ew.Close()
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	for _, entry := range entries {
+		if err := writeFileEntry(ew, entry); err != nil {
+			ew.Close()
+			return nil, err
+		}
+	}
+
+	if err := ew.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Unpack restores an archive into the storage targeted by writer. The whole
+// archive is verified against its manifest before anything is written.
+func Unpack(archivePath string, writer *session.Writer, opts UnpackOptions) (*Manifest, error) {
+	format, err := FormatFromPath(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := verifyArchive(format, archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every session before writing any, so a clash leaves storage as it
+	// was. Overwriting only replaces the sessions themselves, so their message
+	// IDs must not clash with another session's either.
+	for _, sess := range manifest.Sessions {
+		if !opts.Overwrite && writer.SessionExists(sess.ID) {
+			return nil, fmt.Errorf("%w: %s", ErrSessionExists, sess.ID)
+		}
+	}
+	for _, file := range manifest.Files {
+		if file.Kind == session.FileMessage && writer.MessageExists(file.SessionID, file.MessageID) {
+			return nil, fmt.Errorf("%w: message %s is stored for another session", ErrMessageExists, file.MessageID)
+		}
+	}
+
+	if opts.Overwrite {
+		for _, sess := range manifest.Sessions {
+			if err := writer.RemoveSession(sess.ID); err != nil {
+				return nil, fmt.Errorf("failed to remove existing session %s: %w", sess.ID, err)
+			}
+		}
+	}
+
+	files := make(map[string]ManifestFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		files[file.Path] = file
+	}
+
+	er, err := openEntryReader(format, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer er.Close()
+
+	// Session info files are written last so opencode never lists a session
+	// whose messages are still being restored
+	var infoFiles []ManifestFile
+	infoData := make(map[string][]byte)
+
+	for {
+		name, content, err := er.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if name == ManifestName {
+			continue
+		}
+
+		file := files[name]
+		switch file.Kind {
+		case FileSnapshot:
+			// Unpacked sessions belong to the writer's project, which is
+			// where their snapshots are looked up. Objects are
+			// content-addressed, so repositories of several source projects
+			// merge into it without conflict.
+			if err := restoreSnapshotFile(writer.SnapshotDir(), file, content, opts.Overwrite); err != nil {
+				return nil, err
+			}
+		case session.FileSessionInfo:
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			infoFiles = append(infoFiles, file)
+			infoData[file.Path] = data
+		case session.FileMessage:
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if err := writer.WriteMessage(file.SessionID, file.MessageID, data); err != nil {
+				return nil, err
+			}
+		case session.FilePart:
+			data, err := io.ReadAll(content)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", name, err)
+			}
+			if err := writer.WritePart(file.SessionID, file.MessageID, file.PartID, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, file := range infoFiles {
+		if err := writer.WriteSessionInfo(file.SessionID, infoData[file.Path]); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// ReadManifest returns the manifest of an archive without verifying its files
+func ReadManifest(archivePath string) (*Manifest, error) {
+	format, err := FormatFromPath(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	er, err := openEntryReader(format, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer er.Close()
+
+	return readManifestEntry(er)
+}
+
+// verifyArchive checks that the archive holds exactly the files its manifest
+// lists, with matching sizes and checksums
+func verifyArchive(format Format, archivePath string) (*Manifest, error) {
+	er, err := openEntryReader(format, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer er.Close()
+
+	manifest, err := readManifestEntry(er)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]ManifestFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		expected[file.Path] = file
+	}
+
+	seen := make(map[string]bool, len(manifest.Files))
+	for {
+		name, content, err := er.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		file, ok := expected[name]
+		if !ok {
+			return nil, fmt.Errorf("archive entry %s is not listed in the manifest", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("archive entry %s appears more than once", name)
+		}
+		seen[name] = true
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if size != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+			return nil, fmt.Errorf("archive entry %s is corrupted (checksum mismatch)", name)
+		}
+	}
+
+	for _, file := range manifest.Files {
+		if !seen[file.Path] {
+			return nil, fmt.Errorf("archive is missing %s", file.Path)
+		}
+	}
+
+	return manifest, nil
+}
+
+func readManifestEntry(er entryReader) (*Manifest, error) {
+	name, content, err := er.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if name != ManifestName {
+		return nil, fmt.Errorf("not a session archive: first entry is %s, expected %s", name, ManifestName)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(content).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if err := manifest.validate(); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// validate rejects manifests this version cannot restore safely, including
+// any path or ID that could write outside the target directories
+func (m *Manifest) validate() error {
+	if m.Format != ManifestFormat {
+		return fmt.Errorf("unsupported archive format %q", m.Format)
+	}
+	if m.Version < 1 || m.Version > ManifestVersion {
+		return fmt.Errorf("unsupported archive version %d (supported: 1 to %d)", m.Version, ManifestVersion)
+	}
+
+	sessions := make(map[string]bool)
+	for _, sess := range m.Sessions {
+		if !session.IsValidID(sess.ID) {
+			return fmt.Errorf("invalid session ID %q in manifest", sess.ID)
+		}
+		sessions[sess.ID] = true
+	}
+
+	for _, file := range m.Files {
+		if file.Path == ManifestName || file.Path != path.Clean(file.Path) || path.IsAbs(file.Path) || strings.HasPrefix(file.Path, "../") {
+			return fmt.Errorf("invalid archive path %q", file.Path)
+		}
+
+		switch file.Kind {
+		case FileSnapshot:
+			// Version 1 archives hold a single snapshot/ tree with no project
+			if file.Project != "" && !session.IsValidID(file.Project) {
+				return fmt.Errorf("invalid snapshot project %q in manifest", file.Project)
+			}
+			if !strings.HasPrefix(file.Path, snapshotPrefix(file.Project)) {
+				return fmt.Errorf("invalid snapshot path %q", file.Path)
+			}
+			continue
+		case session.FilePart:
+			if !session.IsValidID(file.PartID) {
+				return fmt.Errorf("invalid part ID %q in manifest", file.PartID)
+			}
+			fallthrough
+		case session.FileMessage:
+			if !session.IsValidID(file.MessageID) {
+				return fmt.Errorf("invalid message ID %q in manifest", file.MessageID)
+			}
+		case session.FileSessionInfo:
+		default:
+			return fmt.Errorf("unknown file kind %q for %s", file.Kind, file.Path)
+		}
+
+		if !sessions[file.SessionID] {
+			return fmt.Errorf("file %s belongs to session %q which is not in the manifest", file.Path, file.SessionID)
+		}
+	}
+
+	return nil
+}
+
+// sessionManifestFile returns the manifest record of a storage file, without
+// its size and checksum
+func sessionManifestFile(file session.SessionFile) ManifestFile {
+	return ManifestFile{
+		Path:      archivePath(file),
+		Kind:      file.Kind,
+		SessionID: file.SessionID,
+		MessageID: file.MessageID,
+		PartID:    file.PartID,
+	}
+}
+
+// archivePath returns the layout-neutral name a storage file is archived under
+func archivePath(file session.SessionFile) string {
+	switch file.Kind {
+	case session.FileSessionInfo:
+		return path.Join("sessions", file.SessionID, "info.json")
+	case session.FileMessage:
+		return path.Join("sessions", file.SessionID, "messages", file.MessageID+".json")
+	default:
+		return path.Join("sessions", file.SessionID, "parts", file.MessageID, file.PartID+".json")
+	}
+}
+
+// collectRecords returns entries for a session kept in a database. The
+// records are archived as the JSON files they are read as, so the archive
+// unpacks into either file layout like one packed from files.
+func collectRecords(reader *session.Reader, info *session.SessionInfo) ([]packEntry, error) {
+	files := []session.SessionFile{{Kind: session.FileSessionInfo, SessionID: info.ID}}
+	contents := []json.RawMessage{info.Raw}
+
+	messages, err := reader.ReadMessages(info.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		files = append(files, session.SessionFile{Kind: session.FileMessage, SessionID: info.ID, MessageID: message.ID})
+		contents = append(contents, message.Raw)
+
+		parts, err := reader.ReadMessageParts(info.ID, message.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			files = append(files, session.SessionFile{Kind: session.FilePart, SessionID: info.ID, MessageID: message.ID, PartID: part.ID})
+			contents = append(contents, part.Raw)
+		}
+	}
+
+	entries := make([]packEntry, len(files))
+	for i, file := range files {
+		entries[i] = packEntry{
+			file: sessionManifestFile(file),
+			data: contents[i],
+		}
+	}
+
+	return entries, nil
+}
+
+// isSnapshotData reports whether rel, a slash-separated path inside a
+// snapshot repository, holds snapshot data. Only objects, refs and HEAD are
+// archived; config, hooks and info can make git run commands, so they are
+// never packed or restored.
+func isSnapshotData(rel string) bool {
+	return rel == "HEAD" || strings.HasPrefix(rel, "objects/") || strings.HasPrefix(rel, "refs/")
+}
+
+// snapshotPrefix returns the archive directory holding a project's snapshot
+// files
+func snapshotPrefix(project string) string {
+	return path.Join("snapshot", project) + "/"
+}
+
+// snapshotProject picks the name a snapshot directory is archived under: the
+// project ID it is named after in the hash and SQLite layouts, or the project
+// directory in the legacy layout. Names taken by another directory, or unsafe
+// as a path, fall back to a numbered one.
+func snapshotProject(snapshotDir string, taken map[string]string) string {
+	name := filepath.Base(snapshotDir)
+	if name == "snapshot" {
+		name = filepath.Base(filepath.Dir(snapshotDir))
+	}
+
+	used := make(map[string]bool, len(taken))
+	for _, project := range taken {
+		used[project] = true
+	}
+	if session.IsValidID(name) && !used[name] {
+		return name
+	}
+	for i := 1; ; i++ {
+		if numbered := fmt.Sprintf("project-%d", i); !used[numbered] {
+			return numbered
+		}
+	}
+}
+
+func collectSnapshot(snapshotDir, project string) ([]packEntry, error) {
+	var entries []packEntry
+
+	err := filepath.WalkDir(snapshotDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(snapshotDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !isSnapshotData(rel) {
+			return nil
+		}
+
+		entries = append(entries, packEntry{
+			file: ManifestFile{
+				Path:    snapshotPrefix(project) + rel,
+				Kind:    FileSnapshot,
+				Project: project,
+			},
+			source: p,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	return entries, nil
+}
+
+func hashFile(p string) (int64, string, time.Time, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("failed to open %s: %w", p, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("failed to stat %s: %w", p, err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", time.Time{}, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), stat.ModTime(), nil
+}
+
+func writeFileEntry(ew entryWriter, entry packEntry) error {
+	if entry.data != nil {
+		if err := ew.WriteEntry(entry.file.Path, entry.file.Size, entry.modTime, bytes.NewReader(entry.data)); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", entry.file.Path, err)
+		}
+		return nil
+	}
+
+	file, err := os.Open(entry.source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", entry.source, err)
+	}
+	defer file.Close()
+
+	// Limit to the hashed size so a file growing mid-pack cannot produce an
+	// entry that disagrees with its manifest record
+	content := io.LimitReader(file, entry.file.Size)
+	if err := ew.WriteEntry(entry.file.Path, entry.file.Size, entry.modTime, content); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", entry.source, err)
+	}
+
+	return nil
+}
+
+func restoreSnapshotFile(snapshotDir string, file ManifestFile, content io.Reader, overwrite bool) error {
+	rel := strings.TrimPrefix(file.Path, snapshotPrefix(file.Project))
+	if !isSnapshotData(rel) {
+		// Archives packed before the restriction may carry the repository's
+		// config or hooks; skip them rather than let them run
+		return nil
+	}
+	target := filepath.Join(snapshotDir, filepath.FromSlash(rel))
+
+	// Snapshot objects are content-addressed, so an existing file is almost
+	// always identical and is kept unless the caller asked to overwrite
+	if _, err := os.Stat(target); err == nil && !overwrite {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+
+	if _, err := io.Copy(out, content); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+
+	return out.Close()
+}
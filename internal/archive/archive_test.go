@@ -0,0 +1,358 @@
+package archive
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// writeFiles creates files under dir from slash-separated relative paths
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// hashSession returns the storage files of a one-message session in the hash
+// layout, filed under project
+func hashSession(id, project string) map[string]string {
+	return map[string]string{
+		"storage/session/" + project + "/" + id + ".json": `{"id":"` + id + `","title":"` + id + `","directory":"/work/` + project + `","projectID":"` + project + `","time":{"created":1,"updated":2}}`,
+		"storage/message/" + id + "/msg_" + id + ".json":  `{"id":"msg_` + id + `","sessionID":"` + id + `","role":"user","time":{"created":1}}`,
+		"storage/part/msg_" + id + "/prt_" + id + ".json": `{"id":"prt_` + id + `","sessionID":"` + id + `","messageID":"msg_` + id + `","type":"text","text":"hello"}`,
+	}
+}
+
+// packAll packs every session in the opencode data directory under dataHome
+func packAll(t *testing.T, dataHome string, format Format) (string, *Manifest) {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	reader, err := session.NewGlobalReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionIDs, err := reader.ListSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "sessions."+string(format))
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := Pack(reader, sessionIDs, file, format, PackOptions{})
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return archivePath, manifest
+}
+
+// newTargetWriter returns a writer for an empty hash store and the opencode
+// data directory it writes into
+func newTargetWriter(t *testing.T) (*session.Writer, string) {
+	t.Helper()
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	writer, err := session.NewWriter(t.TempDir(), session.LayoutHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writer, filepath.Join(dataHome, "opencode")
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	for _, format := range []Format{FormatZip, FormatTarZst} {
+		t.Run(string(format), func(t *testing.T) {
+			source := t.TempDir()
+			files := map[string]string{
+				"snapshot/projA/HEAD":          "ref: refs/heads/a\n",
+				"snapshot/projA/objects/aa/01": "object a",
+				"snapshot/projA/config":        "[core]\n",
+				"snapshot/projB/HEAD":          "ref: refs/heads/b\n",
+				"snapshot/projB/objects/bb/01": "object b",
+				"snapshot/projB/hooks/post":    "#!/bin/sh\n",
+			}
+			for name, content := range hashSession("ses_A", "projA") {
+				files[name] = content
+			}
+			for name, content := range hashSession("ses_B", "projB") {
+				files[name] = content
+			}
+			writeFiles(t, filepath.Join(source, "opencode"), files)
+
+			archivePath, manifest := packAll(t, source, format)
+
+			paths := make(map[string]ManifestFile)
+			for _, file := range manifest.Files {
+				paths[file.Path] = file
+			}
+			for _, want := range []string{
+				"sessions/ses_A/info.json",
+				"sessions/ses_A/messages/msg_ses_A.json",
+				"sessions/ses_A/parts/msg_ses_A/prt_ses_A.json",
+				"sessions/ses_B/info.json",
+				"snapshot/projA/HEAD",
+				"snapshot/projA/objects/aa/01",
+				"snapshot/projB/HEAD",
+				"snapshot/projB/objects/bb/01",
+			} {
+				if _, ok := paths[want]; !ok {
+					t.Errorf("manifest is missing %s", want)
+				}
+			}
+			for _, unwanted := range []string{"snapshot/projA/config", "snapshot/projB/hooks/post"} {
+				if _, ok := paths[unwanted]; ok {
+					t.Errorf("manifest includes %s", unwanted)
+				}
+			}
+			if project := paths["snapshot/projB/HEAD"].Project; project != "projB" {
+				t.Errorf("snapshot/projB/HEAD project = %q, want projB", project)
+			}
+
+			writer, target := newTargetWriter(t)
+			if _, err := Unpack(archivePath, writer, UnpackOptions{}); err != nil {
+				t.Fatalf("Unpack: %v", err)
+			}
+
+			// Both projects' objects land in the repository of the project
+			// the sessions were unpacked into
+			snapshotDir := writer.SnapshotDir()
+			for rel, want := range map[string]string{
+				"objects/aa/01": "object a",
+				"objects/bb/01": "object b",
+			} {
+				data, err := os.ReadFile(filepath.Join(snapshotDir, filepath.FromSlash(rel)))
+				if err != nil || string(data) != want {
+					t.Errorf("snapshot %s = %q, %v; want %q", rel, data, err, want)
+				}
+			}
+
+			reader, err := session.NewGlobalReader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{"ses_A", "ses_B"} {
+				sess, err := reader.ReadSession(id)
+				if err != nil {
+					t.Fatalf("ReadSession(%s): %v", id, err)
+				}
+				if len(sess.Messages) != 1 || len(sess.Parts) != 1 {
+					t.Errorf("session %s has %d messages and %d parts after unpacking, want 1 each", id, len(sess.Messages), len(sess.Parts))
+				}
+			}
+
+			if _, err := Unpack(archivePath, writer, UnpackOptions{}); !errors.Is(err, ErrSessionExists) {
+				t.Errorf("second Unpack error = %v, want ErrSessionExists", err)
+			}
+			if _, err := Unpack(archivePath, writer, UnpackOptions{Overwrite: true}); err != nil {
+				t.Errorf("Unpack with Overwrite: %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(target, "storage", "session")); err != nil {
+				t.Errorf("target has no session directory: %v", err)
+			}
+		})
+	}
+}
+
+func TestIsSnapshotData(t *testing.T) {
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"HEAD", true},
+		{"objects/aa/01", true},
+		{"objects/pack/pack-1.pack", true},
+		{"refs/heads/main", true},
+		{"config", false},
+		{"hooks/post-checkout", false},
+		{"info/exclude", false},
+		{"HEAD.lock", false},
+		{"objectsx", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSnapshotData(tt.rel); got != tt.want {
+			t.Errorf("isSnapshotData(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestManifestValidate(t *testing.T) {
+	valid := func() Manifest {
+		return Manifest{
+			Format:   ManifestFormat,
+			Version:  ManifestVersion,
+			Sessions: []ManifestSession{{ID: "ses_A"}},
+			Files: []ManifestFile{
+				{Path: "sessions/ses_A/info.json", Kind: session.FileSessionInfo, SessionID: "ses_A"},
+				{Path: "sessions/ses_A/messages/msg_A.json", Kind: session.FileMessage, SessionID: "ses_A", MessageID: "msg_A"},
+				{Path: "sessions/ses_A/parts/msg_A/prt_A.json", Kind: session.FilePart, SessionID: "ses_A", MessageID: "msg_A", PartID: "prt_A"},
+				{Path: "snapshot/projA/HEAD", Kind: FileSnapshot, Project: "projA"},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *Manifest)
+		err    string
+	}{
+		{"valid", func(m *Manifest) {}, ""},
+		{"version 1 snapshot", func(m *Manifest) {
+			m.Version = 1
+			m.Files[3] = ManifestFile{Path: "snapshot/HEAD", Kind: FileSnapshot}
+		}, ""},
+		{"other format", func(m *Manifest) { m.Format = "zip" }, "unsupported archive format"},
+		{"newer version", func(m *Manifest) { m.Version = ManifestVersion + 1 }, "unsupported archive version"},
+		{"session ID", func(m *Manifest) { m.Sessions[0].ID = "../x" }, "invalid session ID"},
+		{"absolute path", func(m *Manifest) { m.Files[0].Path = "/etc/passwd" }, "invalid archive path"},
+		{"parent path", func(m *Manifest) { m.Files[0].Path = "../info.json" }, "invalid archive path"},
+		{"unclean path", func(m *Manifest) { m.Files[0].Path = "sessions/../../info.json" }, "invalid archive path"},
+		{"manifest path", func(m *Manifest) { m.Files[0].Path = ManifestName }, "invalid archive path"},
+		{"message ID", func(m *Manifest) { m.Files[1].MessageID = "../msg" }, "invalid message ID"},
+		{"part ID", func(m *Manifest) { m.Files[2].PartID = "prt/x" }, "invalid part ID"},
+		{"part message ID", func(m *Manifest) { m.Files[2].MessageID = "" }, "invalid message ID"},
+		{"unknown session", func(m *Manifest) { m.Files[1].SessionID = "ses_B" }, "not in the manifest"},
+		{"unknown kind", func(m *Manifest) { m.Files[0].Kind = "hook" }, "unknown file kind"},
+		{"snapshot outside prefix", func(m *Manifest) { m.Files[3].Path = "sessions/HEAD" }, "invalid snapshot path"},
+		{"snapshot of other project", func(m *Manifest) { m.Files[3].Path = "snapshot/projB/HEAD" }, "invalid snapshot path"},
+		{"snapshot project", func(m *Manifest) {
+			m.Files[3] = ManifestFile{Path: "snapshot/a.b/HEAD", Kind: FileSnapshot, Project: "a.b"}
+		}, "invalid snapshot project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid()
+			tt.modify(&m)
+			err := m.validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("validate() = %v, want error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestUnpackMessageClash(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	source := t.TempDir()
+	writeFiles(t, filepath.Join(source, "opencode"), hashSession("ses_A", "projA"))
+	archivePath, _ := packAll(t, source, FormatZip)
+
+	// Another session in the target already stores parts under the
+	// archive's message ID
+	writer, _ := newTargetWriter(t)
+	if err := writer.WriteMessage("ses_X", "msg_ses_A", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WritePart("ses_X", "msg_ses_A", "prt_X", []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, overwrite := range []bool{false, true} {
+		_, err := Unpack(archivePath, writer, UnpackOptions{Overwrite: overwrite})
+		if !errors.Is(err, ErrMessageExists) {
+			t.Errorf("Unpack(Overwrite: %v) error = %v, want ErrMessageExists", overwrite, err)
+		}
+		if writer.SessionExists("ses_A") {
+			t.Fatalf("Unpack(Overwrite: %v) wrote the session despite the clash", overwrite)
+		}
+	}
+}
+
+func TestPackDatabase(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	source := t.TempDir()
+	dataDir := filepath.Join(source, "opencode")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dataDir, session.DatabaseFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE project (id text PRIMARY KEY, worktree text NOT NULL, name text)",
+		"CREATE TABLE session (id text PRIMARY KEY, project_id text NOT NULL, parent_id text, directory text NOT NULL, title text NOT NULL, version text NOT NULL, share_url text, time_created integer NOT NULL, time_updated integer NOT NULL)",
+		"CREATE TABLE message (id text PRIMARY KEY, session_id text NOT NULL, time_created integer NOT NULL, data text NOT NULL)",
+		"CREATE TABLE part (id text PRIMARY KEY, message_id text NOT NULL, session_id text NOT NULL, time_created integer NOT NULL, data text NOT NULL)",
+		`INSERT INTO project VALUES ('projD', '/work/d', NULL)`,
+		`INSERT INTO session VALUES ('ses_D', 'projD', NULL, '/work/d', 'Database', '1.0.0', NULL, 1, 2)`,
+		`INSERT INTO message VALUES ('msg_D', 'ses_D', 1, '{"role":"user"}')`,
+		`INSERT INTO part VALUES ('prt_D', 'msg_D', 'ses_D', 1, '{"type":"text","text":"from the database"}')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dataDir, map[string]string{"snapshot/projD/HEAD": "ref: refs/heads/d\n"})
+
+	archivePath, manifest := packAll(t, source, FormatTarZst)
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	want := []string{
+		"sessions/ses_D/info.json",
+		"sessions/ses_D/messages/msg_D.json",
+		"sessions/ses_D/parts/msg_D/prt_D.json",
+		"snapshot/projD/HEAD",
+	}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("packed %v, want %v", paths, want)
+	}
+
+	writer, _ := newTargetWriter(t)
+	if _, err := Unpack(archivePath, writer, UnpackOptions{}); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+
+	reader, err := session.NewGlobalReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := reader.ReadSession("ses_D")
+	if err != nil {
+		t.Fatalf("ReadSession: %v", err)
+	}
+	if sess.Info.Title != "Database" || len(sess.Messages) != 1 || len(sess.Parts) != 1 {
+		t.Fatalf("unpacked session %q has %d messages and %d parts", sess.Info.Title, len(sess.Messages), len(sess.Parts))
+	}
+	if text := sess.Parts[0].Text; text == nil || *text != "from the database" {
+		t.Errorf("part text = %v, want %q", text, "from the database")
+	}
+}
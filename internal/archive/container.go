@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is an archive container format
+type Format string
+
+const (
+	FormatZip    Format = "zip"
+	FormatTarZst Format = "tar.zst"
+)
+
+// FormatFromPath picks the container format from an archive file name
+func FormatFromPath(path string) (Format, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return FormatZip, nil
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return FormatTarZst, nil
+	default:
+		return "", fmt.Errorf("unsupported archive extension for %s (use .tar.zst or .zip)", path)
+	}
+}
+
+// entryWriter adds files to an archive in order
+type entryWriter interface {
+	WriteEntry(name string, size int64, modTime time.Time, content io.Reader) error
+	Close() error
+}
+
+// entryReader walks the files of an archive in the order they were written.
+// Next returns io.EOF after the last entry.
+type entryReader interface {
+	Next() (name string, content io.Reader, err error)
+	Close() error
+}
+
+func newEntryWriter(format Format, w io.Writer) (entryWriter, error) {
+	switch format {
+	case FormatZip:
+		return &zipEntryWriter{zw: zip.NewWriter(w)}, nil
+	case FormatTarZst:
+		encoder, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		return &tarEntryWriter{tw: tar.NewWriter(encoder), encoder: encoder}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+func openEntryReader(format Format, path string) (entryReader, error) {
+	switch format {
+	case FormatZip:
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip archive: %w", err)
+		}
+		return &zipEntryReader{zr: zr}, nil
+	case FormatTarZst:
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+		}
+		return &tarEntryReader{tr: tar.NewReader(decoder), decoder: decoder, file: file}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+type zipEntryWriter struct {
+	zw *zip.Writer
+}
+
+func (w *zipEntryWriter) WriteEntry(name string, size int64, modTime time.Time, content io.Reader) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	}
+	header.SetMode(0644)
+
+	fw, err := w.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, content)
+	return err
+}
+
+func (w *zipEntryWriter) Close() error {
+	return w.zw.Close()
+}
+
+type tarEntryWriter struct {
+	tw      *tar.Writer
+	encoder *zstd.Encoder
+}
+
+func (w *tarEntryWriter) WriteEntry(name string, size int64, modTime time.Time, content io.Reader) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+		Format:   tar.FormatPAX,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.Copy(w.tw, content)
+	return err
+}
+
+func (w *tarEntryWriter) Close() error {
+	if err := w.tw.Close(); err != nil {
+		w.encoder.Close()
+		return err
+	}
+	return w.encoder.Close()
+}
+
+type zipEntryReader struct {
+	zr      *zip.ReadCloser
+	next    int
+	current io.ReadCloser
+}
+
+func (r *zipEntryReader) Next() (string, io.Reader, error) {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+
+	for r.next < len(r.zr.File) {
+		file := r.zr.File[r.next]
+		r.next++
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		r.current = rc
+		return file.Name, rc, nil
+	}
+
+	return "", nil, io.EOF
+}
+
+func (r *zipEntryReader) Close() error {
+	if r.current != nil {
+		r.current.Close()
+	}
+	return r.zr.Close()
+}
+
+type tarEntryReader struct {
+	tr      *tar.Reader
+	decoder *zstd.Decoder
+	file    *os.File
+}
+
+func (r *tarEntryReader) Next() (string, io.Reader, error) {
+	for {
+		header, err := r.tr.Next()
+		if err != nil {
+			return "", nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			return header.Name, r.tr, nil
+		}
+	}
+}
+
+func (r *tarEntryReader) Close() error {
+	r.decoder.Close()
+	return r.file.Close()
+}
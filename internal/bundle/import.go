@@ -0,0 +1,235 @@
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// ErrSessionExists is returned by Import when the target storage already
+// holds one of the bundle's sessions
+var ErrSessionExists = errors.New("session already exists")
+
+// ErrMessageExists is returned by Import when another session in the target
+// storage already uses one of the bundle's message IDs. Overwriting does not
+// help, as it only replaces the bundle's own sessions.
+var ErrMessageExists = errors.New("message ID already in use")
+
+// Read decodes and validates a json format document
+func Read(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// Validate checks that the document can be written back into opencode
+// storage: the schema is supported, IDs are well formed and consistent, and
+// every record carries the raw file it will be restored from. Child documents
+// are checked the same way and must name their parent session.
+func (d *Document) Validate() error {
+	return d.validate(&documentIDs{
+		sessions: make(map[string]bool),
+		messages: make(map[string]bool),
+		parts:    make(map[string]bool),
+	})
+}
+
+// documentIDs collects the IDs of a document and its children, which must be
+// unique across all of them
+type documentIDs struct {
+	sessions map[string]bool
+	messages map[string]bool
+	parts    map[string]bool
+}
+
+func (d *Document) validate(ids *documentIDs) error {
+	if d.Schema != SchemaName {
+		return fmt.Errorf("unsupported bundle schema %q (expected %q)", d.Schema, SchemaName)
+	}
+	if d.SchemaVersion < 1 || d.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("unsupported bundle schema version %d (supported: 1 to %d)", d.SchemaVersion, SchemaVersion)
+	}
+
+	sessionID := d.Session.ID
+	if err := validateRecord("session", sessionID, d.Session.Raw); err != nil {
+		return err
+	}
+	if ids.sessions[sessionID] {
+		return fmt.Errorf("duplicate session ID %s", sessionID)
+	}
+	ids.sessions[sessionID] = true
+
+	for _, message := range d.Messages {
+		if err := validateRecord("message", message.ID, message.Raw); err != nil {
+			return err
+		}
+		if message.SessionID != sessionID {
+			return fmt.Errorf("message %s belongs to session %q, not %q", message.ID, message.SessionID, sessionID)
+		}
+		if ids.messages[message.ID] {
+			return fmt.Errorf("duplicate message ID %s", message.ID)
+		}
+		ids.messages[message.ID] = true
+
+		for _, part := range message.Parts {
+			if err := validateRecord("part", part.ID, part.Raw); err != nil {
+				return err
+			}
+			if part.MessageID != message.ID {
+				return fmt.Errorf("part %s is listed under message %s but belongs to %q", part.ID, message.ID, part.MessageID)
+			}
+			if part.SessionID != sessionID {
+				return fmt.Errorf("part %s belongs to session %q, not %q", part.ID, part.SessionID, sessionID)
+			}
+			if ids.parts[part.ID] {
+				return fmt.Errorf("duplicate part ID %s", part.ID)
+			}
+			ids.parts[part.ID] = true
+		}
+	}
+
+	for _, child := range d.Children {
+		if child == nil {
+			return fmt.Errorf("session %s has an empty child document", sessionID)
+		}
+		if child.Session.ParentID == nil || *child.Session.ParentID != sessionID {
+			return fmt.Errorf("child session %s does not name %s as its parent", child.Session.ID, sessionID)
+		}
+		if err := child.validate(ids); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// documents returns d and its children, each parent before its children
+func (d *Document) documents() []*Document {
+	docs := []*Document{d}
+	for _, child := range d.Children {
+		docs = append(docs, child.documents()...)
+	}
+	return docs
+}
+
+// validateRecord checks an ID and that the raw file it will be restored from
+// is a JSON object describing the same ID
+func validateRecord(kind, id string, raw json.RawMessage) error {
+	if !session.IsValidID(id) {
+		return fmt.Errorf("invalid %s ID %q", kind, id)
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("%s %s has no raw data; the bundle cannot be restored losslessly", kind, id)
+	}
+
+	var fields struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return fmt.Errorf("%s %s has malformed raw data: %w", kind, id, err)
+	}
+	if fields.ID != id {
+		return fmt.Errorf("%s %s raw data describes ID %q", kind, id, fields.ID)
+	}
+
+	return nil
+}
+
+// ImportOptions controls how Import treats existing sessions
+type ImportOptions struct {
+	// Overwrite replaces an existing session with the same ID instead of failing
+	Overwrite bool
+}
+
+// ImportResult summarizes what Import wrote. Messages and Parts include
+// those of child sessions.
+type ImportResult struct {
+	SessionID string
+	Title     string
+	Children  int // Child sessions imported along with the session
+	Messages  int
+	Parts     int
+}
+
+// Import writes a validated document and its child documents into opencode
+// storage through writer
+func Import(doc *Document, writer *session.Writer, opts ImportOptions) (*ImportResult, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	docs := doc.documents()
+
+	// Check every session before writing any, so a clash leaves storage as it
+	// was. Overwriting only replaces the sessions themselves, so their message
+	// IDs must not clash with another session's either.
+	for _, d := range docs {
+		sessionID := d.Session.ID
+		if !opts.Overwrite && writer.SessionExists(sessionID) {
+			return nil, fmt.Errorf("%w: %s", ErrSessionExists, sessionID)
+		}
+		for _, message := range d.Messages {
+			if writer.MessageExists(sessionID, message.ID) {
+				return nil, fmt.Errorf("%w: message %s is stored for another session", ErrMessageExists, message.ID)
+			}
+		}
+	}
+
+	if opts.Overwrite {
+		for _, d := range docs {
+			if err := writer.RemoveSession(d.Session.ID); err != nil {
+				return nil, fmt.Errorf("failed to remove existing session: %w", err)
+			}
+		}
+	}
+
+	result := &ImportResult{
+		SessionID: doc.Session.ID,
+		Title:     doc.Session.Title,
+		Children:  len(docs) - 1,
+	}
+
+	// Children go first so a parent session is only listed once the sessions
+	// of its task calls are in place
+	for i := len(docs) - 1; i >= 0; i-- {
+		if err := importDocument(docs[i], writer, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// importDocument writes one session's messages, parts and info, counting them
+// in result
+func importDocument(doc *Document, writer *session.Writer, result *ImportResult) error {
+	sessionID := doc.Session.ID
+
+	// Messages and parts go first so opencode never sees a session whose
+	// info file exists but whose content is still being written
+	for _, message := range doc.Messages {
+		for _, part := range message.Parts {
+			if err := writer.WritePart(sessionID, message.ID, part.ID, part.Raw); err != nil {
+				return err
+			}
+			result.Parts++
+		}
+
+		if err := writer.WriteMessage(sessionID, message.ID, message.Raw); err != nil {
+			return err
+		}
+		result.Messages++
+	}
+
+	return writer.WriteSessionInfo(sessionID, doc.Session.Raw)
+}
@@ -0,0 +1,103 @@
+package bundle
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testDocument returns a valid document with one message, one part and one
+// child session
+func testDocument() *Document {
+	parentID := "ses_parent"
+	return &Document{
+		Schema:        SchemaName,
+		SchemaVersion: SchemaVersion,
+		Session:       SessionRecord{ID: "ses_parent", Raw: json.RawMessage(`{"id":"ses_parent"}`)},
+		Messages: []MessageRecord{{
+			ID:        "msg_a",
+			SessionID: "ses_parent",
+			Raw:       json.RawMessage(`{"id":"msg_a"}`),
+			Parts: []PartRecord{{
+				ID:        "prt_a",
+				MessageID: "msg_a",
+				SessionID: "ses_parent",
+				Raw:       json.RawMessage(`{"id":"prt_a"}`),
+			}},
+		}},
+		Children: []*Document{{
+			Schema:        SchemaName,
+			SchemaVersion: SchemaVersion,
+			Session:       SessionRecord{ID: "ses_child", ParentID: &parentID, Raw: json.RawMessage(`{"id":"ses_child"}`)},
+		}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(d *Document)
+		err    string
+	}{
+		{"valid", func(d *Document) {}, ""},
+
+		// IDs become file names, so any that could leave the storage
+		// directory are rejected
+		{"session path escape", func(d *Document) {
+			d.Session.ID = "../../etc/passwd"
+			d.Session.Raw = json.RawMessage(`{"id":"../../etc/passwd"}`)
+		}, "invalid session ID"},
+		{"message path escape", func(d *Document) {
+			d.Messages[0].ID = "../msg"
+			d.Messages[0].Raw = json.RawMessage(`{"id":"../msg"}`)
+		}, "invalid message ID"},
+		{"part path escape", func(d *Document) {
+			d.Messages[0].Parts[0].ID = "prt/../../x"
+			d.Messages[0].Parts[0].Raw = json.RawMessage(`{"id":"prt/../../x"}`)
+		}, "invalid part ID"},
+		{"absolute session ID", func(d *Document) {
+			d.Session.ID = "/tmp/x"
+			d.Session.Raw = json.RawMessage(`{"id":"/tmp/x"}`)
+		}, "invalid session ID"},
+		{"empty message ID", func(d *Document) {
+			d.Messages[0].ID = ""
+		}, "invalid message ID"},
+		{"child path escape", func(d *Document) {
+			d.Children[0].Session.ID = ".."
+			d.Children[0].Session.Raw = json.RawMessage(`{"id":".."}`)
+		}, "invalid session ID"},
+
+		{"schema", func(d *Document) { d.Schema = "other" }, "unsupported bundle schema"},
+		{"newer version", func(d *Document) { d.SchemaVersion = SchemaVersion + 1 }, "unsupported bundle schema version"},
+		{"missing raw", func(d *Document) { d.Messages[0].Raw = nil }, "has no raw data"},
+		{"raw of another ID", func(d *Document) { d.Messages[0].Parts[0].Raw = json.RawMessage(`{"id":"prt_b"}`) }, `describes ID "prt_b"`},
+		{"malformed raw", func(d *Document) { d.Session.Raw = json.RawMessage(`[1]`) }, "malformed raw data"},
+		{"message of another session", func(d *Document) { d.Messages[0].SessionID = "ses_other" }, "belongs to session"},
+		{"part of another message", func(d *Document) { d.Messages[0].Parts[0].MessageID = "msg_b" }, "is listed under message"},
+		{"part of another session", func(d *Document) { d.Messages[0].Parts[0].SessionID = "ses_other" }, "belongs to session"},
+		{"duplicate message", func(d *Document) { d.Messages = append(d.Messages, d.Messages[0]) }, "duplicate message ID"},
+		{"duplicate session in child", func(d *Document) {
+			d.Children[0].Session.ID = "ses_parent"
+			d.Children[0].Session.Raw = d.Session.Raw
+		}, "duplicate session ID"},
+		{"child of another parent", func(d *Document) { d.Children[0].Session.ParentID = nil }, "does not name ses_parent as its parent"},
+		{"empty child", func(d *Document) { d.Children[0] = nil }, "empty child document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := testDocument()
+			tt.modify(doc)
+			err := doc.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.err)
+			}
+		})
+	}
+}
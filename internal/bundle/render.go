@@ -0,0 +1,98 @@
+package bundle
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+func init() {
+	render.Register("json", func(render.Options) render.Renderer {
+		return &JSONRenderer{}
+	})
+	render.Register("jsonl", func(render.Options) render.Renderer {
+		return &JSONLRenderer{}
+	})
+}
+
+// JSONRenderer writes a session as a single indented Document
+type JSONRenderer struct{}
+
+// Render writes the session document to w
+func (r *JSONRenderer) Render(w io.Writer, sess *session.Session) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(NewDocument(sess, time.Now()))
+}
+
+// Extension returns the file extension for json exports
+func (r *JSONRenderer) Extension() string {
+	return ".json"
+}
+
+// MIMEType returns the media type of json exports
+func (r *JSONRenderer) MIMEType() string {
+	return "application/json"
+}
+
+// JSONLRenderer writes one PartLine per message part, in transcript order.
+// The lines of child sessions follow those of their parent.
+type JSONLRenderer struct{}
+
+// Render streams the session's part records to w
+func (r *JSONLRenderer) Render(w io.Writer, sess *session.Session) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return r.encode(encoder, NewDocument(sess, time.Now()))
+}
+
+func (r *JSONLRenderer) encode(encoder *json.Encoder, doc *Document) error {
+	ref := SessionRef{ID: doc.Session.ID, ParentID: doc.Session.ParentID, Title: doc.Session.Title}
+
+	for messageIndex, message := range doc.Messages {
+		parts := message.Parts
+
+		// Message raw data is only in the json document; repeating it on
+		// every part line would multiply the output size
+		message.Parts = nil
+		message.Raw = nil
+
+		for partIndex, part := range parts {
+			line := PartLine{
+				Schema:        SchemaName,
+				SchemaVersion: SchemaVersion,
+				Session:       ref,
+				MessageIndex:  messageIndex,
+				PartIndex:     partIndex,
+				Message:       message,
+				Part:          part,
+			}
+			if err := encoder.Encode(&line); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, child := range doc.Children {
+		if err := r.encode(encoder, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Extension returns the file extension for jsonl exports
+func (r *JSONLRenderer) Extension() string {
+	return ".jsonl"
+}
+
+// MIMEType returns the media type of jsonl exports
+func (r *JSONLRenderer) MIMEType() string {
+	return "application/x-ndjson"
+}
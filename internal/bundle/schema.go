@@ -0,0 +1,267 @@
+// Package bundle defines the versioned JSON schema used to export complete
+// sessions losslessly, and the json and jsonl formats built on it.
+//
+// The normalized fields give downstream consumers a stable shape that does
+// not change when opencode's on-disk layout does. Every record also carries
+// the untouched source file under "raw", so nothing opencode stored is lost.
+// See docs/export-schema.md for the field reference.
+package bundle
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// SchemaName identifies documents and records produced by this package
+const SchemaName = "opencode-session-export/session"
+
+// SchemaVersion is incremented whenever a field is removed, renamed or changes
+// meaning. Adding optional fields does not change the version.
+const SchemaVersion = 1
+
+// Document is the top-level object written by the json format
+type Document struct {
+	Schema        string          `json:"schema"`
+	SchemaVersion int             `json:"schemaVersion"`
+	ExportedAt    time.Time       `json:"exportedAt"`
+	Session       SessionRecord   `json:"session"`
+	Messages      []MessageRecord `json:"messages"`
+	// Children holds a document per child session when the export includes
+	// them, such as the sessions of subagent task calls
+	Children []*Document `json:"children,omitempty"`
+}
+
+// SessionRecord is the normalized session metadata
+type SessionRecord struct {
+	ID       string          `json:"id"`
+	ParentID *string         `json:"parentID,omitempty"`
+	Title    string          `json:"title"`
+	Version  string          `json:"version,omitempty"`
+	Created  *time.Time      `json:"created,omitempty"`
+	Updated  *time.Time      `json:"updated,omitempty"`
+	ShareURL *string         `json:"shareURL,omitempty"`
+	Raw      json.RawMessage `json:"raw,omitempty"`
+}
+
+// MessageRecord is a normalized message together with its parts
+type MessageRecord struct {
+	ID           string     `json:"id"`
+	SessionID    string     `json:"sessionID"`
+	Role         string     `json:"role"`
+	Created      *time.Time `json:"created,omitempty"`
+	Completed    *time.Time `json:"completed,omitempty"`
+	Model        *string    `json:"model,omitempty"`
+	Provider     *string    `json:"provider,omitempty"`
+	Cost         *float64   `json:"cost,omitempty"`
+	InputTokens  *int       `json:"inputTokens,omitempty"`
+	OutputTokens *int       `json:"outputTokens,omitempty"`
+	// Reasoning and cache counts are only set for messages that record them
+	ReasoningTokens  *int            `json:"reasoningTokens,omitempty"`
+	CacheReadTokens  *int            `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens *int            `json:"cacheWriteTokens,omitempty"`
+	Mode             *string         `json:"mode,omitempty"`
+	Cwd              *string         `json:"cwd,omitempty"`
+	Root             *string         `json:"root,omitempty"`
+	Error            *ErrorRecord    `json:"error,omitempty"`
+	Parts            []PartRecord    `json:"parts,omitempty"`
+	Raw              json.RawMessage `json:"raw,omitempty"`
+}
+
+// ErrorRecord is the error an assistant message ended with
+type ErrorRecord struct {
+	Name    string `json:"name"`
+	Message string `json:"message,omitempty"`
+}
+
+// PartRecord is a normalized message part. Tool is set for tool parts and
+// holds the decoded tool state.
+type PartRecord struct {
+	ID        string          `json:"id"`
+	MessageID string          `json:"messageID"`
+	SessionID string          `json:"sessionID"`
+	Type      string          `json:"type"`
+	Start     *time.Time      `json:"start,omitempty"`
+	End       *time.Time      `json:"end,omitempty"`
+	Text      *string         `json:"text,omitempty"`
+	Tool      *ToolRecord     `json:"tool,omitempty"`
+	Raw       json.RawMessage `json:"raw,omitempty"`
+}
+
+// ToolRecord is the decoded state of a tool execution
+type ToolRecord struct {
+	Name     string          `json:"name"`
+	CallID   string          `json:"callID,omitempty"`
+	Status   string          `json:"status"`
+	Title    *string         `json:"title,omitempty"`
+	Input    json.RawMessage `json:"input,omitempty"`
+	Output   interface{}     `json:"output,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+	Start    *time.Time      `json:"start,omitempty"`
+	End      *time.Time      `json:"end,omitempty"`
+}
+
+// PartLine is a single record of the jsonl format. Each line is
+// self-describing so it can be processed without the lines around it.
+type PartLine struct {
+	Schema        string        `json:"schema"`
+	SchemaVersion int           `json:"schemaVersion"`
+	Session       SessionRef    `json:"session"`
+	MessageIndex  int           `json:"messageIndex"`
+	PartIndex     int           `json:"partIndex"`
+	Message       MessageRecord `json:"message"`
+	Part          PartRecord    `json:"part"`
+}
+
+// SessionRef identifies the session a jsonl record belongs to
+type SessionRef struct {
+	ID       string  `json:"id"`
+	ParentID *string `json:"parentID,omitempty"`
+	Title    string  `json:"title"`
+}
+
+// NewDocument converts a session into a schema document
+func NewDocument(sess *session.Session, exportedAt time.Time) *Document {
+	partsByMessage := make(map[string][]session.MessagePart)
+	for _, part := range sess.Parts {
+		partsByMessage[part.MessageID] = append(partsByMessage[part.MessageID], part)
+	}
+
+	doc := &Document{
+		Schema:        SchemaName,
+		SchemaVersion: SchemaVersion,
+		ExportedAt:    exportedAt.UTC(),
+		Session:       newSessionRecord(&sess.Info),
+		Messages:      make([]MessageRecord, 0, len(sess.Messages)),
+	}
+
+	for i := range sess.Messages {
+		record := newMessageRecord(&sess.Messages[i])
+		for _, part := range partsByMessage[sess.Messages[i].ID] {
+			record.Parts = append(record.Parts, newPartRecord(&part))
+		}
+		doc.Messages = append(doc.Messages, record)
+	}
+
+	for _, child := range sess.Children {
+		doc.Children = append(doc.Children, NewDocument(child, exportedAt))
+	}
+
+	return doc
+}
+
+func newSessionRecord(info *session.SessionInfo) SessionRecord {
+	return SessionRecord{
+		ID:       info.ID,
+		ParentID: info.ParentID,
+		Title:    info.Title,
+		Version:  info.Version,
+		Created:  millisToTime(info.Time.Created),
+		Updated:  millisToTime(info.Time.Updated),
+		ShareURL: info.ShareURL,
+		Raw:      info.Raw,
+	}
+}
+
+func newMessageRecord(msg *session.Message) MessageRecord {
+	record := MessageRecord{
+		ID:           msg.ID,
+		SessionID:    msg.SessionID,
+		Role:         msg.Role,
+		Model:        msg.Model,
+		Provider:     msg.Provider,
+		Cost:         msg.Cost,
+		InputTokens:  msg.InputTokens,
+		OutputTokens: msg.OutputTokens,
+		Mode:         msg.Mode,
+		Raw:          msg.Raw,
+	}
+
+	if msg.Time != nil {
+		record.Created = millisToTime(msg.Time.Created)
+	}
+	if msg.CompletedAt != nil {
+		record.Completed = millisToTime(*msg.CompletedAt)
+	}
+	if msg.Tokens != nil && msg.Schema != session.SchemaFlat {
+		tokens := *msg.Tokens
+		record.ReasoningTokens = &tokens.Reasoning
+		record.CacheReadTokens = &tokens.Cache.Read
+		record.CacheWriteTokens = &tokens.Cache.Write
+	}
+	if msg.Path != nil {
+		record.Cwd = &msg.Path.Cwd
+		record.Root = &msg.Path.Root
+	}
+	if msg.Error != nil {
+		record.Error = &ErrorRecord{Name: msg.Error.Name, Message: msg.Error.Message}
+	}
+
+	return record
+}
+
+func newPartRecord(part *session.MessagePart) PartRecord {
+	record := PartRecord{
+		ID:        part.ID,
+		MessageID: part.MessageID,
+		SessionID: part.SessionID,
+		Type:      part.Type,
+		Text:      part.Text,
+		Raw:       part.Raw,
+	}
+
+	if part.Time != nil {
+		record.Start = millisToTime(part.Time.Start)
+		record.End = millisToTime(part.Time.End)
+	}
+
+	if part.Type == "tool" {
+		record.Tool = decodeTool(part)
+	}
+
+	return record
+}
+
+// decodeTool reads the tool state from the part fields, falling back to the
+// older layout where the whole tool payload lived under "data"
+func decodeTool(part *session.MessagePart) *ToolRecord {
+	var toolData session.ToolPartData
+
+	if part.Tool != nil && part.State != nil {
+		toolData.Tool = *part.Tool
+		if part.CallID != nil {
+			toolData.CallID = *part.CallID
+		}
+		if err := json.Unmarshal(part.State, &toolData.State); err != nil {
+			return nil
+		}
+	} else if err := json.Unmarshal(part.Data, &toolData); err != nil {
+		return nil
+	}
+
+	record := &ToolRecord{
+		Name:     toolData.Tool,
+		CallID:   toolData.CallID,
+		Status:   toolData.State.Status,
+		Title:    toolData.State.Title,
+		Input:    toolData.State.Input,
+		Output:   toolData.State.Output,
+		Metadata: toolData.State.Metadata,
+	}
+
+	if toolData.State.Time != nil {
+		record.Start = millisToTime(toolData.State.Time.Start)
+		record.End = millisToTime(toolData.State.Time.End)
+	}
+
+	return record
+}
+
+func millisToTime(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms).UTC()
+	return &t
+}
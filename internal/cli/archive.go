@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fantomc0der/opencode-session-export/internal/archive"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+func runPack(args []string) error {
+	packFlags := flag.NewFlagSet("pack", flag.ExitOnError)
+	sessionID := packFlags.String("session", "", "Session ID to pack")
+	latest := packFlags.Bool("latest", false, "Pack the most recent session")
+	all := packFlags.Bool("all", false, "Pack all sessions")
+	since := packFlags.String("since", "", "Pack sessions since date (YYYY-MM-DD)")
+	output := packFlags.String("output", "", "Archive file to create (.tar.zst or .zip)")
+	projectPath := packFlags.String("project", "", "Project path (default: current directory)")
+	skipSnapshots := packFlags.Bool("skip-snapshots", false, "Leave out the project's snapshot repository")
+	packFlags.Parse(args)
+
+	if *output == "" {
+		return fmt.Errorf("must specify --output <file.tar.zst|file.zip>")
+	}
+
+	format, err := archive.FormatFromPath(*output)
+	if err != nil {
+		return err
+	}
+
+	if *projectPath == "" {
+		*projectPath, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	reader, err := session.NewReader(*projectPath)
+	if err != nil {
+		return fmt.Errorf("failed to create session reader: %w", err)
+	}
+
+	sessionIDs, err := selectSessions(reader, *sessionID, *latest, *all, *since)
+	if err != nil {
+		return err
+	}
+	if len(sessionIDs) == 0 {
+		fmt.Println("No sessions to pack.")
+		return nil
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	manifest, err := archive.Pack(reader, sessionIDs, file, format, archive.PackOptions{
+		SkipSnapshots: *skipSnapshots,
+	})
+	if err != nil {
+		file.Close()
+		os.Remove(*output)
+		return fmt.Errorf("failed to pack sessions: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	fmt.Printf("Packed %d session(s), %d file(s) into %s\n", len(manifest.Sessions), len(manifest.Files), *output)
+	return nil
+}
+
+func runUnpack(args []string) error {
+	unpackFlags := flag.NewFlagSet("unpack", flag.ExitOnError)
+	projectPath := unpackFlags.String("project", "", "Project to unpack into (default: current directory)")
+	layout := unpackFlags.String("layout", "", "Storage layout: legacy or hash (default: same as existing storage)")
+	force := unpackFlags.Bool("force", false, "Overwrite sessions that already exist")
+	list := unpackFlags.Bool("list", false, "List the archive contents without unpacking")
+	unpackFlags.Parse(args)
+
+	if unpackFlags.NArg() != 1 {
+		return fmt.Errorf("usage: unpack [options] <archive>")
+	}
+	archivePath := unpackFlags.Arg(0)
+
+	if *list {
+		manifest, err := archive.ReadManifest(archivePath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Archive created %s from %s storage, %d file(s):\n\n",
+			manifest.CreatedAt.Local().Format("2006-01-02 15:04"),
+			manifest.SourceLayout,
+			len(manifest.Files))
+		for _, sess := range manifest.Sessions {
+			fmt.Printf("  %s - %s\n", sess.ID, sess.Title)
+		}
+		return nil
+	}
+
+	if *projectPath == "" {
+		var err error
+		*projectPath, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	writer, err := session.NewWriter(*projectPath, session.Layout(*layout))
+	if err != nil {
+		return fmt.Errorf("failed to create session writer: %w", err)
+	}
+
+	manifest, err := archive.Unpack(archivePath, writer, archive.UnpackOptions{Overwrite: *force})
+	if errors.Is(err, archive.ErrSessionExists) {
+		return fmt.Errorf("%w (use --force to overwrite)", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", archivePath, err)
+	}
+
+	fmt.Printf("Unpacked %d session(s) into %s storage at %s\n", len(manifest.Sessions), writer.Layout(), writer.StorageDir())
+	for _, sess := range manifest.Sessions {
+		fmt.Printf("  %s - %s\n", sess.ID, sess.Title)
+	}
+
+	return nil
+}
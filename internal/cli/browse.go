@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+	"github.com/fantomc0der/opencode-session-export/internal/tui"
+)
+
+func runBrowse(args []string) error {
+	browseFlags := flag.NewFlagSet("browse", flag.ExitOnError)
+	all := browseFlags.Bool("all", false, "Browse sessions from all projects")
+	projectPath := browseFlags.String("project", "", "Project path (default: current directory)")
+	outputDir := browseFlags.String("output-dir", "./exports", "Directory exports are written to")
+	format := browseFlags.String("format", "markdown", "Export format")
+	browseFlags.Parse(args)
+
+	renderer, err := render.New(*format, render.Options{})
+	if err != nil {
+		return err
+	}
+
+	// The preview is always markdown since it is the most readable as plain text
+	previewRenderer, err := render.New("markdown", render.Options{})
+	if err != nil {
+		return err
+	}
+
+	sessions, readers, err := collectSessions(*all, *projectPath)
+	if err != nil {
+		return err
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found.")
+		return nil
+	}
+
+	items := make([]tui.Item, 0, len(sessions))
+	for _, sess := range sessions {
+		detail := sess.Info.GetUpdatedAt().Format("2006-01-02 15:04")
+		if *all {
+			detail = fmt.Sprintf("[%s] %s", sess.ProjectName, detail)
+		}
+		items = append(items, tui.Item{
+			ID:     sess.SessionID,
+			Title:  sess.Info.Title,
+			Detail: detail,
+		})
+	}
+
+	return tui.Run(tui.Options{
+		Items: items,
+		Preview: func(item tui.Item) (string, error) {
+			sess, err := readers[item.ID].ReadSession(item.ID)
+			if err != nil {
+				return "", err
+			}
+
+			var buf bytes.Buffer
+			if err := previewRenderer.Render(&buf, sess); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		Export: func(items []tui.Item) (string, error) {
+			if err := os.MkdirAll(*outputDir, 0755); err != nil {
+				return "", fmt.Errorf("failed to create output directory: %w", err)
+			}
+
+			var lastFile string
+			for _, item := range items {
+				sess, err := readers[item.ID].ReadSession(item.ID)
+				if err != nil {
+					return "", fmt.Errorf("failed to read session %s: %w", item.ID, err)
+				}
+
+				lastFile = filepath.Join(*outputDir, exportFilename(renderer, sess))
+				if err := renderToFile(renderer, sess, lastFile); err != nil {
+					return "", err
+				}
+			}
+
+			if len(items) == 1 {
+				return fmt.Sprintf("Exported session %s to %s", shortID(items[0].ID), lastFile), nil
+			}
+			return fmt.Sprintf("Exported %d sessions to %s", len(items), *outputDir), nil
+		},
+	})
+}
+
+// collectSessions lists the sessions of one project, or of every project when
+// all is set, most recently updated first. The map gives the reader for each
+// session ID since sessions of different projects live in different stores.
+func collectSessions(all bool, projectPath string) ([]session.SessionWithProject, map[string]*session.Reader, error) {
+	readers := make(map[string]*session.Reader)
+
+	if all {
+		reader, err := session.NewGlobalReader()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create global session reader: %w", err)
+		}
+
+		sessions, err := reader.ListAllSessions()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list all sessions: %w", err)
+		}
+		for _, sess := range sessions {
+			readers[sess.SessionID] = sess.Reader()
+		}
+		return sessions, readers, nil
+	}
+
+	if projectPath == "" {
+		var err error
+		projectPath, err = os.Getwd()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	reader, err := session.NewReader(projectPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session reader: %w", err)
+	}
+
+	sessionIDs, err := reader.ListSessions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	projectName := filepath.Base(projectPath)
+	if absPath, err := filepath.Abs(projectPath); err == nil {
+		projectName = filepath.Base(absPath)
+	}
+
+	var sessions []session.SessionWithProject
+	for _, sessionID := range sessionIDs {
+		info, err := reader.ReadSessionInfo(sessionID)
+		if err != nil {
+			continue // Skip sessions with unreadable metadata
+		}
+		sessions = append(sessions, session.SessionWithProject{
+			SessionID:   sessionID,
+			ProjectName: projectName,
+			Info:        *info,
+		})
+		readers[sessionID] = reader
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Info.GetUpdatedAt().After(sessions[j].Info.GetUpdatedAt())
+	})
+
+	return sessions, readers, nil
+}
@@ -1,15 +1,25 @@
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/fantomc0der/opencode-session-export/internal/markdown"
+	"github.com/fantomc0der/opencode-session-export/internal/bundle"
+	"github.com/fantomc0der/opencode-session-export/internal/config"
+	"github.com/fantomc0der/opencode-session-export/internal/redact"
+	"github.com/fantomc0der/opencode-session-export/internal/render"
 	"github.com/fantomc0der/opencode-session-export/internal/session"
+
+	// Export formats register themselves with the render package
+	_ "github.com/fantomc0der/opencode-session-export/internal/html"
+	_ "github.com/fantomc0der/opencode-session-export/internal/markdown"
 )
 
 // Execute runs the CLI application
@@ -25,6 +35,22 @@ func Execute() error {
 		return runList(os.Args[2:])
 	case "export":
 		return runExport()
+	case "sync":
+		return runSync(os.Args[2:])
+	case "search":
+		return runSearch(os.Args[2:])
+	case "stats":
+		return runStats(os.Args[2:])
+	case "index":
+		return runIndex(os.Args[2:])
+	case "browse":
+		return runBrowse(os.Args[2:])
+	case "import":
+		return runImport(os.Args[2:])
+	case "pack":
+		return runPack(os.Args[2:])
+	case "unpack":
+		return runUnpack(os.Args[2:])
 	case "help", "-h", "--help":
 		printUsage()
 		return nil
@@ -42,13 +68,22 @@ USAGE:
     opencode-session-export <command> [options]
 
 COMMANDS:
-    list [--all]            List available sessions (--all for all projects)
-    export                  Export session(s) to markdown
+    list [--all] [--tree]   List available sessions (--all for all projects)
+    export                  Export session(s) to markdown, HTML or JSON
+    sync --output-dir <dir> Re-export only sessions that changed since the last sync
+    search <query>          Search titles, messages and tool calls across all projects
+    stats [--all]           Report cost, token and tool usage
+    index rebuild|status    Rebuild or inspect the search index
+    browse [--all]          Browse, preview and export sessions interactively
+    import <bundle>         Import a session from a JSON export into opencode storage
+    pack                    Pack session(s) into a portable .tar.zst or .zip archive
+    unpack <archive>        Restore sessions from an archive created by pack
     help                    Show this help message
 
 LIST OPTIONS:
     --all                   List sessions from all projects (grouped by project)
     --recent                List all sessions chronologically by last update
+    --tree                  Show child (subagent) sessions under the sessions that started them
 
 EXPORT OPTIONS:
     --session <id>          Export specific session by ID
@@ -57,15 +92,109 @@ EXPORT OPTIONS:
     --output <file>         Output file (default: stdout)
     --output-dir <dir>      Output directory for multiple sessions
     --project <path>        Project path (default: current directory)
-    --include-costs         Include cost information in output
+    --format <name>         Output format: markdown, html, json or jsonl (default: markdown)
+    --include-costs         Include costs and tokens per step and message, with session totals
     --include-timings       Include timing information in output
-    --include-snapshots     Include snapshot information in output
+    --include-snapshots     Show the snapshot each step recorded and the files each step changed
+    --snapshot-diffs        Also diff those changes against opencode's snapshot repository (implies
+                            --include-snapshots)
+    --include-files-changed Add a summary of the lines added and removed per file
+    --reasoning <mode>      Model reasoning: show, collapse (fold it away) or omit (default: collapse)
+    --layout <name>         Part order within a message: interleaved (as created) or grouped (text,
+                            then attachments, then tool calls) (default: interleaved)
+    --step-dividers         Mark where each step of an assistant message starts
     --since <date>          Export sessions since date (YYYY-MM-DD)
+    --redact                Redact secrets, emails, IPs and home paths; reports counts on stderr
+    --redact-config <file>  JSON file with extra rules or disabled built-ins (implies --redact;
+                            default: redact.json in the user config directory)
+    --watch                 Keep --output (or files in --output-dir) current as sessions change,
+                            until interrupted
+    --jobs <n>              Files and sessions to read in parallel (default: number of CPUs)
+    --with-children         Nest child (subagent) sessions under the task calls that started them,
+                            instead of exporting them as separate files
+
+SYNC OPTIONS:
+    --output-dir <dir>      Directory to keep in sync (required)
+    --all                   Sync sessions from all projects
+    --project <path>        Project path (default: current directory)
+    --format, --include-costs, --include-timings, --include-snapshots, --snapshot-diffs,
+    --include-files-changed, --reasoning, --layout, --step-dividers, --redact, --redact-config
+                            As for export; changing any of them re-renders every session
+    --prune                 Delete exports of sessions that no longer exist (default: keep and
+                            flag them in the manifest)
+    The directory's .ocse-sync.json manifest records each session's file, last update
+    and content hash. Files follow title changes instead of being duplicated.
+
+SEARCH OPTIONS:
+    --project <path>        Only search this project (default: all projects)
+    --regex                 Treat the query as a regular expression
+    --case-sensitive        Match case exactly (default: ignore case)
+    --role <roles>          Only search these roles, comma-separated (user, assistant)
+    --tool <names>          Only search input and output of these tools, comma-separated
+    --since <date>          Only search content from this date on (YYYY-MM-DD)
+    --until <date>          Only search content up to and including this date (YYYY-MM-DD)
+    --limit <n>             Stop after n matches
+    --no-index              Read every session instead of using the search index
+
+STATS OPTIONS:
+    --all                   Report on sessions from all projects
+    --project <path>        Project path (default: current directory)
+    --since <date>          Only count messages from this date on (YYYY-MM-DD)
+    --until <date>          Only count messages up to and including this date (YYYY-MM-DD)
+    --format <name>         Output format: table, csv or json (default: table)
+    --top <n>               Number of most expensive sessions to list (default: 10, 0 for all)
+    --output <file>         Output file (default: stdout)
+
+INDEX COMMANDS:
+    index rebuild           Discard the search index and build it from scratch
+    index status            Show the index size and how many sessions changed since it was updated
+    --project <path>        Also cover this project's sessions
+    The index lives in the user cache directory and is refreshed by every search,
+    re-reading only sessions whose files changed.
+
+BROWSE OPTIONS:
+    --all                   Browse sessions from all projects
+    --project <path>        Project path (default: current directory)
+    --output-dir <dir>      Directory exports are written to (default: ./exports)
+    --format <name>         Export format (default: markdown)
+    Keys: ↑/↓ or j/k move, tab switches to the preview pane, PgUp/PgDn scroll
+    the preview, / filters by title, space selects, a selects all, e or enter
+    exports the selected sessions (or the highlighted one), q quits
+
+IMPORT OPTIONS:
+    --project <path>        Project to import into (default: current directory)
+    --layout <name>         Storage layout: legacy or hash (default: same as existing storage)
+    --force                 Overwrite a session that already exists
+
+PACK OPTIONS:
+    --session, --latest, --all, --since, --project
+                            Select sessions as for export
+    --output <file>         Archive to create; .tar.zst or .zip picks the format
+    --skip-snapshots        Leave out the project's snapshot repository
+
+UNPACK OPTIONS:
+    --project <path>        Project to unpack into (default: current directory)
+    --layout <name>         Storage layout: legacy or hash (default: same as existing storage)
+    --force                 Overwrite sessions that already exist
+    --list                  Show the archive contents without unpacking
 
 EXAMPLES:
     opencode-session-export list
     opencode-session-export export --session abc123 --output session.md
     opencode-session-export export --latest --output latest.md
+    opencode-session-export export --latest --with-children --output latest.md
+    opencode-session-export export --latest --format html --output latest.html
+    opencode-session-export export --all --format jsonl --output-dir ./raw/
+    opencode-session-export export --latest --watch --output live.md
+    opencode-session-export export --latest --redact --output ticket.md
+    opencode-session-export sync --all --output-dir ~/archive/opencode --prune
+    opencode-session-export search "migration bug"
+    opencode-session-export search --regex --tool bash --since 2024-06-01 'go test .*-run'
+    opencode-session-export stats --all --since 2024-06-01 --until 2024-06-30 --format csv
+    opencode-session-export browse --all --format html
+    opencode-session-export import --project ~/src/app session.json
+    opencode-session-export pack --all --output sessions.tar.zst
+    opencode-session-export unpack --project ~/src/app sessions.tar.zst
     opencode-session-export export --all --output-dir ./exports/
     opencode-session-export export --since 2024-01-01 --include-costs --output-dir ./exports/`)
 }
@@ -75,10 +204,11 @@ func runList(args []string) error {
 	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
 	all := listFlags.Bool("all", false, "List sessions from all projects")
 	recent := listFlags.Bool("recent", false, "List sessions chronologically by last update")
+	tree := listFlags.Bool("tree", false, "Show child sessions under the sessions that started them")
 	listFlags.Parse(args)
 
 	if *all || *recent {
-		return runListAll(*recent)
+		return runListAll(*recent, *tree)
 	}
 
 	// Default behavior: list sessions from current project only
@@ -105,6 +235,7 @@ func runList(args []string) error {
 	fmt.Printf("Found %d session(s) in current project:\n\n", len(sessionIDs))
 
 	// Get session info for each session
+	var sessions []session.SessionWithProject
 	for _, sessionID := range sessionIDs {
 		info, err := reader.ReadSessionInfo(sessionID)
 		if err != nil {
@@ -112,16 +243,77 @@ func runList(args []string) error {
 			continue
 		}
 
+		if *tree {
+			sessions = append(sessions, session.SessionWithProject{SessionID: sessionID, Info: *info})
+			continue
+		}
+
 		fmt.Printf("  %s - %s (%s)\n",
-			sessionID[:8],
+			shortID(sessionID),
 			info.Title,
 			info.GetUpdatedAt().Format("2006-01-02 15:04"))
 	}
 
+	if *tree {
+		printSessionTree(sessions, false)
+	}
+
 	return nil
 }
 
-func runListAll(chronological bool) error {
+// printSessionTree lists sessions with each child session indented under its
+// parent, oldest child first. Sessions whose parent is not among sessions are
+// listed at the top level, in the order given.
+func printSessionTree(sessions []session.SessionWithProject, withProject bool) {
+	listed := make(map[string]bool, len(sessions))
+	for _, sess := range sessions {
+		listed[sess.SessionID] = true
+	}
+
+	var roots []session.SessionWithProject
+	children := make(map[string][]session.SessionWithProject)
+	for _, sess := range sessions {
+		if parentID := sess.Info.ParentID; parentID != nil && *parentID != sess.SessionID && listed[*parentID] {
+			children[*parentID] = append(children[*parentID], sess)
+		} else {
+			roots = append(roots, sess)
+		}
+	}
+	for _, siblings := range children {
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].Info.Time.Created < siblings[j].Info.Time.Created
+		})
+	}
+
+	var printNode func(sess session.SessionWithProject, depth int)
+	printNode = func(sess session.SessionWithProject, depth int) {
+		indent := "  "
+		if depth > 0 {
+			indent += strings.Repeat("   ", depth-1) + "└─ "
+		}
+		project := ""
+		if withProject {
+			project = fmt.Sprintf("[%s] ", sess.ProjectName)
+		}
+
+		fmt.Printf("%s%s - %s%s (%s)\n",
+			indent,
+			shortID(sess.SessionID),
+			project,
+			sess.Info.Title,
+			sess.Info.GetUpdatedAt().Format("2006-01-02 15:04"))
+
+		for _, child := range children[sess.SessionID] {
+			printNode(child, depth+1)
+		}
+	}
+
+	for _, sess := range roots {
+		printNode(sess, 0)
+	}
+}
+
+func runListAll(chronological, tree bool) error {
 	reader, err := session.NewGlobalReader()
 	if err != nil {
 		return fmt.Errorf("failed to create global session reader: %w", err)
@@ -139,30 +331,50 @@ func runListAll(chronological bool) error {
 
 	fmt.Printf("Found %d session(s) across all projects:\n\n", len(allSessions))
 
-	if chronological {
+	if chronological && tree {
+		printSessionTree(allSessions, true)
+	} else if chronological {
 		// Display sessions chronologically
 		for _, sess := range allSessions {
 			fmt.Printf("  %s - [%s] %s (%s)\n",
-				sess.SessionID[:8],
+				shortID(sess.SessionID),
 				sess.ProjectName,
 				sess.Info.Title,
 				sess.Info.GetUpdatedAt().Format("2006-01-02 15:04"))
 		}
 	} else {
-		// Group sessions by project
+		// Group sessions by project path where the layout records one, since
+		// different projects can share a name; most recently active first
+		var projectKeys []string
 		projectSessions := make(map[string][]session.SessionWithProject)
 		for _, sess := range allSessions {
-			projectSessions[sess.ProjectName] = append(projectSessions[sess.ProjectName], sess)
+			key := sess.ProjectPath
+			if key == "" {
+				key = sess.ProjectName
+			}
+			if _, ok := projectSessions[key]; !ok {
+				projectKeys = append(projectKeys, key)
+			}
+			projectSessions[key] = append(projectSessions[key], sess)
 		}
 
 		// Display sessions grouped by project
-		for projectName, sessions := range projectSessions {
-			fmt.Printf("Project: %s\n", projectName)
-			for _, sess := range sessions {
-				fmt.Printf("  %s - %s (%s)\n",
-					sess.SessionID[:8],
-					sess.Info.Title,
-					sess.Info.GetUpdatedAt().Format("2006-01-02 15:04"))
+		for _, key := range projectKeys {
+			sessions := projectSessions[key]
+			if path := sessions[0].ProjectPath; path != "" {
+				fmt.Printf("Project: %s (%s)\n", sessions[0].ProjectName, path)
+			} else {
+				fmt.Printf("Project: %s\n", sessions[0].ProjectName)
+			}
+			if tree {
+				printSessionTree(sessions, false)
+			} else {
+				for _, sess := range sessions {
+					fmt.Printf("  %s - %s (%s)\n",
+						shortID(sess.SessionID),
+						sess.Info.Title,
+						sess.Info.GetUpdatedAt().Format("2006-01-02 15:04"))
+				}
 			}
 			fmt.Println()
 		}
@@ -181,10 +393,21 @@ func runExport() error {
 	output := exportFlags.String("output", "", "Output file (default: stdout)")
 	outputDir := exportFlags.String("output-dir", "", "Output directory for multiple sessions")
 	projectPath := exportFlags.String("project", "", "Project path (default: current directory)")
+	format := exportFlags.String("format", "markdown", "Output format ("+strings.Join(render.Names(), ", ")+")")
 	includeCosts := exportFlags.Bool("include-costs", false, "Include cost information")
 	includeTimings := exportFlags.Bool("include-timings", false, "Include timing information")
 	includeSnapshots := exportFlags.Bool("include-snapshots", false, "Include snapshot information")
+	snapshotDiffs := exportFlags.Bool("snapshot-diffs", false, "Diff step changes against the snapshot repository")
+	includeFilesChanged := exportFlags.Bool("include-files-changed", false, "Summarize the lines added and removed per file")
+	reasoning := exportFlags.String("reasoning", "collapse", "How to show model reasoning (show, collapse, omit)")
+	layout := exportFlags.String("layout", "interleaved", "Part order within a message (interleaved, grouped)")
+	stepDividers := exportFlags.Bool("step-dividers", false, "Mark where each step of a message starts")
 	since := exportFlags.String("since", "", "Export sessions since date (YYYY-MM-DD)")
+	redactSecrets := exportFlags.Bool("redact", false, "Redact secrets and personal data before rendering")
+	redactConfig := exportFlags.String("redact-config", "", "Redaction rules file (implies --redact)")
+	watchMode := exportFlags.Bool("watch", false, "Keep regenerating the output as the session changes")
+	withChildren := exportFlags.Bool("with-children", false, "Nest child sessions under the task calls that started them")
+	jobs := exportFlags.Int("jobs", runtime.NumCPU(), "Number of files and sessions to read in parallel")
 
 	exportFlags.Parse(os.Args[2:])
 
@@ -197,30 +420,263 @@ func runExport() error {
 		}
 	}
 
+	if *jobs < 1 {
+		return fmt.Errorf("--jobs must be at least 1")
+	}
+
+	if *watchMode && *withChildren {
+		return fmt.Errorf("--with-children cannot be combined with --watch")
+	}
+
+	reasoningMode, err := render.ParseReasoningMode(*reasoning)
+	if err != nil {
+		return err
+	}
+	partLayout, err := render.ParseLayout(*layout)
+	if err != nil {
+		return err
+	}
+
 	reader, err := session.NewReader(*projectPath)
 	if err != nil {
 		return fmt.Errorf("failed to create session reader: %w", err)
 	}
+	reader.SetJobs(*jobs)
+	reader.SetSnapshots(*snapshotDiffs)
+
+	redactor, err := newRedactor(*redactSecrets, *redactConfig)
+	if err != nil {
+		return err
+	}
+
+	renderOptions := render.Options{
+		IncludeCosts:        *includeCosts,
+		IncludeTimings:      *includeTimings,
+		IncludeSnapshots:    *includeSnapshots || *snapshotDiffs,
+		SnapshotDiffs:       *snapshotDiffs,
+		IncludeFilesChanged: *includeFilesChanged,
+		Reasoning:           reasoningMode,
+		Layout:              partLayout,
+		StepDividers:        *stepDividers,
+	}
+	if redactor != nil {
+		renderOptions.Redactor = redactor
+	}
+	renderer, err := render.New(*format, renderOptions)
+	if err != nil {
+		return err
+	}
+
+	sessionsToExport, err := selectSessions(reader, *sessionID, *latest, *all, *since)
+	if err != nil {
+		return err
+	}
+
+	if len(sessionsToExport) == 0 {
+		fmt.Println("No sessions to export.")
+		return nil
+	}
+
+	if *watchMode {
+		return watchSessions(reader, renderer, redactor, sessionsToExport, *output, *outputDir)
+	}
+
+	// Child sessions are exported inside their parents instead of on their own
+	if *withChildren {
+		sessionsToExport = withoutNestedSessions(reader, sessionsToExport)
+	}
+
+	// Export sessions
+	if len(sessionsToExport) == 1 && *outputDir == "" {
+		// Single session export
+		return exportSingleSession(reader, renderer, redactor, sessionsToExport[0], *output, *withChildren)
+	} else {
+		// Multiple sessions export
+		if *outputDir == "" {
+			*outputDir = "./exports"
+		}
+		return exportMultipleSessions(reader, renderer, redactor, sessionsToExport, *outputDir, *jobs, *withChildren)
+	}
+}
+
+// withoutNestedSessions drops the sessions whose parent session is also in
+// sessionIDs, since that parent's export includes them
+func withoutNestedSessions(reader *session.Reader, sessionIDs []string) []string {
+	selected := make(map[string]bool, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		selected[sessionID] = true
+	}
+
+	var roots []string
+	for _, sessionID := range sessionIDs {
+		info, err := reader.ReadSessionInfo(sessionID)
+		if err == nil && info.ParentID != nil && selected[*info.ParentID] {
+			continue
+		}
+		roots = append(roots, sessionID)
+	}
+	return roots
+}
 
-	// Create markdown generator
-	generator := markdown.NewGenerator(markdown.Options{
-		IncludeCosts:     *includeCosts,
-		IncludeTimings:   *includeTimings,
-		IncludeSnapshots: *includeSnapshots,
+func exportSingleSession(reader *session.Reader, renderer render.Renderer, redactor *redact.Redactor, sessionID, outputFile string, withChildren bool) error {
+	_, note, err := writeExport(reader, renderer, redactor, sessionID, withChildren, func(*session.Session) string {
+		return outputFile
 	})
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	if err != nil {
+		return err
+	}
+
+	if outputFile != "" {
+		fmt.Printf("Exported session %s to %s\n", shortID(sessionID), outputFile)
+	}
+
+	return nil
+}
+
+// exportMultipleSessions exports up to jobs sessions at once. Progress is
+// printed in the order the sessions were given, whichever finishes first.
+func exportMultipleSessions(reader *session.Reader, renderer render.Renderer, redactor *redact.Redactor, sessionIDs []string, outputDir string, jobs int, withChildren bool) error {
+	// Create output directory
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Printf("Exporting %d session(s) to %s...\n", len(sessionIDs), outputDir)
+
+	type exportResult struct {
+		filename string
+		note     string // Redaction summary, if any
+		err      error
+	}
+
+	// One buffered channel per session lets workers finish in any order
+	// while results are still reported in sequence
+	results := make([]chan exportResult, len(sessionIDs))
+	for i := range results {
+		results[i] = make(chan exportResult, 1)
+	}
 
-	// Determine which sessions to export
-	var sessionsToExport []string
+	next := make(chan int)
+	go func() {
+		for i := range sessionIDs {
+			next <- i
+		}
+		close(next)
+	}()
+
+	for w := 0; w < min(jobs, len(sessionIDs)); w++ {
+		go func() {
+			for i := range next {
+				var result exportResult
+				_, result.note, result.err = writeExport(reader, renderer, redactor, sessionIDs[i], withChildren, func(sess *session.Session) string {
+					result.filename = exportFilename(renderer, sess)
+					return filepath.Join(outputDir, result.filename)
+				})
+				results[i] <- result
+			}
+		}()
+	}
+
+	failed := 0
+	for i, sessionID := range sessionIDs {
+		result := <-results[i]
+		if result.note != "" {
+			fmt.Fprintln(os.Stderr, result.note)
+		}
+		if result.err != nil {
+			failed++
+			fmt.Printf("Warning: %v\n", result.err)
+			continue
+		}
+
+		fmt.Printf("  [%d/%d] %s -> %s\n", i+1, len(sessionIDs), shortID(sessionID), result.filename)
+	}
+
+	if failed > 0 {
+		fmt.Printf("Export complete with %d failure(s). Files saved to %s\n", failed, outputDir)
+		return fmt.Errorf("%d session(s) failed to export", failed)
+	}
+	fmt.Printf("Export complete! Files saved to %s\n", outputDir)
+	return nil
+}
+
+func runImport(args []string) error {
+	importFlags := flag.NewFlagSet("import", flag.ExitOnError)
+	projectPath := importFlags.String("project", "", "Project to import into (default: current directory)")
+	layout := importFlags.String("layout", "", "Storage layout: legacy or hash (default: same as existing storage)")
+	force := importFlags.Bool("force", false, "Overwrite a session that already exists")
+	importFlags.Parse(args)
+
+	if importFlags.NArg() != 1 {
+		return fmt.Errorf("usage: import [options] <bundle.json>")
+	}
+	bundlePath := importFlags.Arg(0)
+
+	if *projectPath == "" {
+		var err error
+		*projectPath, err = os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer file.Close()
+
+	doc, err := bundle.Read(file)
+	if err != nil {
+		return fmt.Errorf("invalid bundle %s: %w", bundlePath, err)
+	}
 
-	if *sessionID != "" {
-		sessionsToExport = []string{*sessionID}
-	} else if *latest {
+	writer, err := session.NewWriter(*projectPath, session.Layout(*layout))
+	if err != nil {
+		return fmt.Errorf("failed to create session writer: %w", err)
+	}
+
+	result, err := bundle.Import(doc, writer, bundle.ImportOptions{Overwrite: *force})
+	if errors.Is(err, bundle.ErrSessionExists) {
+		return fmt.Errorf("%w (use --force to overwrite)", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import session: %w", err)
+	}
+
+	children := ""
+	if result.Children > 0 {
+		children = fmt.Sprintf(", %d child session(s)", result.Children)
+	}
+	fmt.Printf("Imported session %s - %s (%d message(s), %d part(s)%s) into %s storage at %s\n",
+		result.SessionID,
+		result.Title,
+		result.Messages,
+		result.Parts,
+		children,
+		writer.Layout(),
+		writer.StorageDir())
+
+	return nil
+}
+
+// selectSessions resolves the --session, --latest, --all and --since flags
+// shared by commands that act on sessions of a single project
+func selectSessions(reader *session.Reader, sessionID string, latest, all bool, since string) ([]string, error) {
+	var selected []string
+
+	if sessionID != "" {
+		selected = []string{sessionID}
+	} else if latest {
 		sessions, err := reader.ListSessions()
 		if err != nil {
-			return fmt.Errorf("failed to list sessions: %w", err)
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
 		}
 		if len(sessions) == 0 {
-			return fmt.Errorf("no sessions found")
+			return nil, fmt.Errorf("no sessions found")
 		}
 
 		// Find the latest session by reading update times
@@ -239,24 +695,25 @@ func runExport() error {
 		}
 
 		if latestSession == "" {
-			return fmt.Errorf("no valid sessions found")
+			return nil, fmt.Errorf("no valid sessions found")
 		}
 
-		sessionsToExport = []string{latestSession}
-	} else if *all {
-		sessionsToExport, err = reader.ListSessions()
+		selected = []string{latestSession}
+	} else if all {
+		var err error
+		selected, err = reader.ListSessions()
 		if err != nil {
-			return fmt.Errorf("failed to list sessions: %w", err)
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
 		}
-	} else if *since != "" {
-		sinceTime, err := time.Parse("2006-01-02", *since)
+	} else if since != "" {
+		sinceTime, err := time.Parse("2006-01-02", since)
 		if err != nil {
-			return fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
+			return nil, fmt.Errorf("invalid date format (use YYYY-MM-DD): %w", err)
 		}
 
 		allSessions, err := reader.ListSessions()
 		if err != nil {
-			return fmt.Errorf("failed to list sessions: %w", err)
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
 		}
 
 		for _, sid := range allSessions {
@@ -265,94 +722,126 @@ func runExport() error {
 				continue
 			}
 			if info.GetCreatedAt().After(sinceTime) {
-				sessionsToExport = append(sessionsToExport, sid)
+				selected = append(selected, sid)
 			}
 		}
 	} else {
-		return fmt.Errorf("must specify --session, --latest, --all, or --since")
+		return nil, fmt.Errorf("must specify --session, --latest, --all, or --since")
 	}
 
-	if len(sessionsToExport) == 0 {
-		fmt.Println("No sessions to export.")
-		return nil
+	return selected, nil
+}
+
+// newRedactor builds the redactor for --redact and --redact-config. It
+// returns nil when redaction is off. Without --redact-config, rules are read
+// from redact.json in the config directory if that file exists.
+func newRedactor(enabled bool, configPath string) (*redact.Redactor, error) {
+	if !enabled && configPath == "" {
+		return nil, nil
 	}
 
-	// Export sessions
-	if len(sessionsToExport) == 1 && *outputDir == "" {
-		// Single session export
-		return exportSingleSession(reader, generator, sessionsToExport[0], *output)
-	} else {
-		// Multiple sessions export
-		if *outputDir == "" {
-			*outputDir = "./exports"
+	if configPath == "" {
+		configDir, err := config.GetConfigDir()
+		if err == nil {
+			defaultPath := filepath.Join(configDir, "redact.json")
+			if _, err := os.Stat(defaultPath); err == nil {
+				configPath = defaultPath
+			}
 		}
-		return exportMultipleSessions(reader, generator, sessionsToExport, *outputDir)
 	}
-}
 
-func exportSingleSession(reader *session.Reader, generator *markdown.Generator, sessionID, outputFile string) error {
-	sess, err := reader.ReadSession(sessionID)
-	if err != nil {
-		return fmt.Errorf("failed to read session: %w", err)
+	var cfg *redact.Config
+	if configPath != "" {
+		var err error
+		cfg, err = redact.LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	markdown, err := generator.Generate(sess)
+	rules, err := redact.BuildRules(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to generate markdown: %w", err)
+		return nil, err
 	}
 
-	if outputFile == "" {
-		fmt.Print(markdown)
-	} else {
-		if err := os.WriteFile(outputFile, []byte(markdown), 0644); err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
-		}
-		fmt.Printf("Exported session %s to %s\n", sessionID[:8], outputFile)
-	}
+	return redact.New(rules), nil
+}
 
-	return nil
+// loadSession reads a session and, when redactor is set, scrubs it and
+// reports the substitutions on stderr so they never mix with stdout exports
+func loadSession(reader *session.Reader, redactor *redact.Redactor, sessionID string) (*session.Session, error) {
+	sess, note, err := readSession(reader, redactor, sessionID, false)
+	if err != nil {
+		return nil, err
+	}
+	if note != "" {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	return sess, nil
 }
 
-func exportMultipleSessions(reader *session.Reader, generator *markdown.Generator, sessionIDs []string, outputDir string) error {
-	// Create output directory
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// readSession is loadSession without printing, for callers loading several
+// sessions at once. It returns the redaction summary instead. With
+// withChildren set, the session's child sessions are read into it as well.
+func readSession(reader *session.Reader, redactor *redact.Redactor, sessionID string, withChildren bool) (*session.Session, string, error) {
+	read := reader.ReadSession
+	if withChildren {
+		read = reader.ReadSessionTree
 	}
 
-	fmt.Printf("Exporting %d session(s) to %s...\n", len(sessionIDs), outputDir)
+	sess, err := read(sessionID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
 
-	for i, sessionID := range sessionIDs {
-		sess, err := reader.ReadSession(sessionID)
-		if err != nil {
-			fmt.Printf("Warning: failed to read session %s: %v\n", sessionID[:8], err)
-			continue
-		}
+	if redactor == nil {
+		return sess, "", nil
+	}
 
-		markdown, err := generator.Generate(sess)
-		if err != nil {
-			fmt.Printf("Warning: failed to generate markdown for session %s: %v\n", sessionID[:8], err)
-			continue
-		}
+	report, err := redactor.RedactSession(sess)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to redact session %s: %w", sessionID, err)
+	}
 
-		// Create filename from session title and ID
-		filename := fmt.Sprintf("%s_%s.md",
-			sanitizeFilename(sess.Info.Title),
-			sessionID[:8])
+	return sess, redactionNote(redactor, sessionID, report), nil
+}
 
-		outputFile := filepath.Join(outputDir, filename)
+// renderToFile renders a session into outputFile, creating or truncating it
+func renderToFile(renderer render.Renderer, sess *session.Session, outputFile string) error {
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", outputFile, err)
+	}
 
-		if err := os.WriteFile(outputFile, []byte(markdown), 0644); err != nil {
-			fmt.Printf("Warning: failed to write %s: %v\n", filename, err)
-			continue
-		}
+	if err := renderer.Render(file, sess); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to render session %s: %w", sess.Info.ID, err)
+	}
 
-		fmt.Printf("  [%d/%d] %s -> %s\n", i+1, len(sessionIDs), sessionID[:8], filename)
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
 	}
 
-	fmt.Printf("Export complete! Files saved to %s\n", outputDir)
 	return nil
 }
 
+// exportFilename builds an output filename from the session title and ID
+func exportFilename(renderer render.Renderer, sess *session.Session) string {
+	return fmt.Sprintf("%s_%s%s",
+		sanitizeFilename(sess.Info.Title),
+		shortID(sess.Info.ID),
+		renderer.Extension())
+}
+
+// shortID abbreviates a session ID for display and filenames. IDs from
+// imported or unpacked sessions may be shorter than the usual eight characters.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
 func sanitizeFilename(name string) string {
 	// Replace invalid filename characters
 	invalid := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
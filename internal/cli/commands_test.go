@@ -0,0 +1,19 @@
+package cli
+
+import "testing"
+
+func TestShortID(t *testing.T) {
+	tests := []struct {
+		id, want string
+	}{
+		{"ses_A1aaaaaaaaaa", "ses_A1aa"},
+		{"ses_A1aa", "ses_A1aa"},
+		{"abc", "abc"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := shortID(tt.id); got != tt.want {
+			t.Errorf("shortID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
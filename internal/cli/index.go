@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/config"
+	"github.com/fantomc0der/opencode-session-export/internal/index"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+func runIndex(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: index <rebuild|status> [options]")
+	}
+
+	switch args[0] {
+	case "rebuild":
+		return runIndexRebuild(args[1:])
+	case "status":
+		return runIndexStatus(args[1:])
+	default:
+		return fmt.Errorf("unknown index command: %s (use rebuild or status)", args[0])
+	}
+}
+
+func runIndexRebuild(args []string) error {
+	rebuildFlags := flag.NewFlagSet("index rebuild", flag.ExitOnError)
+	projectPath := rebuildFlags.String("project", "", "Also index this project's sessions")
+	rebuildFlags.Parse(args)
+
+	indexPath, err := searchIndexPath()
+	if err != nil {
+		return err
+	}
+
+	sources, err := indexScope(*projectPath)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ix := index.New()
+	result := ix.Refresh(sources, true)
+	if err := ix.Save(indexPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Indexed %d session(s), %d field(s), %d term(s) in %s\n",
+		len(ix.Sessions), ix.Fields(), len(ix.Terms), time.Since(start).Round(time.Millisecond))
+	if result.Failed > 0 {
+		fmt.Printf("Skipped %d unreadable session(s)\n", result.Failed)
+	}
+	fmt.Printf("Index saved to %s\n", indexPath)
+
+	return nil
+}
+
+func runIndexStatus(args []string) error {
+	statusFlags := flag.NewFlagSet("index status", flag.ExitOnError)
+	projectPath := statusFlags.String("project", "", "Also check this project's sessions")
+	statusFlags.Parse(args)
+
+	indexPath, err := searchIndexPath()
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := os.Stat(indexPath)
+	if os.IsNotExist(err) {
+		fmt.Printf("No search index at %s (run 'index rebuild' or any search to create it)\n", indexPath)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat search index: %w", err)
+	}
+
+	ix, err := index.Open(indexPath)
+	if err != nil {
+		return err
+	}
+
+	sources, err := indexScope(*projectPath)
+	if err != nil {
+		return err
+	}
+	check := ix.Check(sources, true)
+
+	fmt.Printf("Index:     %s (%s)\n", indexPath, formatBytes(fileInfo.Size()))
+	if !ix.Updated.IsZero() {
+		fmt.Printf("Updated:   %s\n", ix.Updated.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("Sessions:  %d\n", len(ix.Sessions))
+	fmt.Printf("Fields:    %d\n", ix.Fields())
+	fmt.Printf("Terms:     %d\n", len(ix.Terms))
+	fmt.Printf("Freshness: %d up to date, %d new, %d changed, %d deleted\n",
+		check.Unchanged, check.Added, check.Updated, check.Removed)
+
+	return nil
+}
+
+// searchIndexPath returns where the search index is stored
+func searchIndexPath() (string, error) {
+	cacheDir, err := config.GetCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get cache directory: %w", err)
+	}
+	return index.DefaultPath(cacheDir), nil
+}
+
+// indexScope lists the sessions of all projects, plus those of projectPath
+// when set, as index sources
+func indexScope(projectPath string) ([]index.Source, error) {
+	sessions, readers, err := collectSessions(true, "")
+	if err != nil {
+		return nil, err
+	}
+	sources := indexSources(sessions, readers)
+
+	if projectPath != "" {
+		sessions, readers, err := collectSessions(false, projectPath)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, indexSources(sessions, readers)...)
+	}
+
+	return sources, nil
+}
+
+func indexSources(sessions []session.SessionWithProject, readers map[string]*session.Reader) []index.Source {
+	sources := make([]index.Source, 0, len(sessions))
+	for _, sess := range sessions {
+		sources = append(sources, index.Source{
+			SessionID:   sess.SessionID,
+			ProjectName: sess.ProjectName,
+			Reader:      readers[sess.SessionID],
+		})
+	}
+	return sources
+}
+
+// formatBytes formats a byte count for display
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/fantomc0der/opencode-session-export/internal/index"
+	"github.com/fantomc0der/opencode-session-export/internal/search"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+func runSearch(args []string) error {
+	searchFlags := flag.NewFlagSet("search", flag.ExitOnError)
+	projectPath := searchFlags.String("project", "", "Only search this project (default: all projects)")
+	regex := searchFlags.Bool("regex", false, "Treat the query as a regular expression")
+	caseSensitive := searchFlags.Bool("case-sensitive", false, "Match case exactly")
+	roles := searchFlags.String("role", "", "Only search messages with these roles (comma-separated: user, assistant)")
+	tools := searchFlags.String("tool", "", "Only search input and output of these tools (comma-separated)")
+	since := searchFlags.String("since", "", "Only search content from this date on (YYYY-MM-DD)")
+	until := searchFlags.String("until", "", "Only search content up to and including this date (YYYY-MM-DD)")
+	limit := searchFlags.Int("limit", 0, "Stop after this many matches (0: no limit)")
+	noIndex := searchFlags.Bool("no-index", false, "Read every session instead of using the search index")
+	searchFlags.Parse(args)
+
+	if searchFlags.NArg() == 0 {
+		return fmt.Errorf("usage: search [options] <query>")
+	}
+
+	query, err := search.NewQuery(strings.Join(searchFlags.Args(), " "), *regex, *caseSensitive)
+	if err != nil {
+		return fmt.Errorf("invalid query: %w", err)
+	}
+	query.Roles = splitList(*roles)
+	query.Tools = splitList(*tools)
+
+	if *since != "" {
+		query.Since, err = time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --since date (use YYYY-MM-DD): %w", err)
+		}
+	}
+	if *until != "" {
+		untilDate, err := time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --until date (use YYYY-MM-DD): %w", err)
+		}
+		// The range includes the whole --until day
+		query.Until = untilDate.AddDate(0, 0, 1)
+	}
+
+	sessions, readers, err := collectSessions(*projectPath == "", *projectPath)
+	if err != nil {
+		return err
+	}
+
+	// Highlight with color on a terminal and with markdown bold otherwise
+	opener, closer := "**", "**"
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		opener, closer = "\x1b[1;31m", "\x1b[0m"
+	}
+
+	var matches []search.Match
+	if *noIndex {
+		matches = scanSessions(query, sessions, readers)
+	} else {
+		matches, err = searchIndex(query, sessions, readers, *projectPath == "")
+		if err != nil {
+			return err
+		}
+	}
+
+	if *limit > 0 && len(matches) > *limit {
+		matches = matches[:*limit]
+	}
+
+	byID := make(map[string]session.SessionWithProject, len(sessions))
+	for _, sess := range sessions {
+		byID[sess.SessionID] = sess
+	}
+
+	matchCount := len(matches)
+	sessionCount := 0
+	for start := 0; start < len(matches); {
+		end := start + 1
+		for end < len(matches) && matches[end].SessionID == matches[start].SessionID {
+			end++
+		}
+		printSearchMatches(byID[matches[start].SessionID], matches[start:end], opener, closer)
+		sessionCount++
+		start = end
+	}
+
+	if matchCount == 0 {
+		fmt.Println("No matches found.")
+		return nil
+	}
+
+	fmt.Printf("%d match(es) in %d session(s)\n", matchCount, sessionCount)
+	return nil
+}
+
+// scanSessions searches by reading every session in the query's date range
+func scanSessions(query *search.Query, sessions []session.SessionWithProject, readers map[string]*session.Reader) []search.Match {
+	var matches []search.Match
+	for _, sessInfo := range sessions {
+		if !query.SessionInRange(&sessInfo.Info) {
+			continue
+		}
+
+		sess, err := readers[sessInfo.SessionID].ReadSession(sessInfo.SessionID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read session %s: %v\n", sessInfo.SessionID, err)
+			continue
+		}
+
+		matches = append(matches, query.Session(sess, sessInfo.ProjectName)...)
+	}
+	return matches
+}
+
+// searchIndex brings the search index up to date for the given sessions,
+// saves it if anything changed, and runs the query against it
+func searchIndex(query *search.Query, sessions []session.SessionWithProject, readers map[string]*session.Reader, complete bool) ([]search.Match, error) {
+	indexPath, err := searchIndexPath()
+	if err != nil {
+		return nil, err
+	}
+
+	ix, err := index.Open(indexPath)
+	if err != nil {
+		// The index is only a cache, so a damaged one is rebuilt
+		fmt.Fprintf(os.Stderr, "Warning: %v; rebuilding\n", err)
+		ix = index.New()
+	}
+
+	// Only a search of all projects lists every session, so only it can tell
+	// which indexed sessions were deleted
+	result := ix.Refresh(indexSources(sessions, readers), complete)
+	if result.Changed() {
+		if err := ix.Save(indexPath); err != nil {
+			return nil, err
+		}
+	}
+
+	scope := make([]string, 0, len(sessions))
+	for _, sess := range sessions {
+		scope = append(scope, sess.SessionID)
+	}
+
+	return ix.Search(query, scope), nil
+}
+
+// printSearchMatches prints one session's matches under a session header
+func printSearchMatches(sessInfo session.SessionWithProject, matches []search.Match, opener, closer string) {
+	fmt.Printf("%s [%s] %s (%s)\n",
+		sessInfo.SessionID,
+		sessInfo.ProjectName,
+		sessInfo.Info.Title,
+		sessInfo.Info.GetUpdatedAt().Format("2006-01-02 15:04"))
+
+	for _, match := range matches {
+		field := match.Field
+
+		var location string
+		switch field.Kind {
+		case search.KindTitle:
+			location = "title"
+		case search.KindInput, search.KindOutput:
+			location = fmt.Sprintf("message %d (%s) %s %s", field.MessageNum, field.Role, field.Tool, field.Kind)
+		default:
+			location = fmt.Sprintf("message %d (%s) %s", field.MessageNum, field.Role, field.Kind)
+		}
+		if match.Count > 1 {
+			location += fmt.Sprintf(", %d matches", match.Count)
+		}
+
+		fmt.Printf("  %s: %s\n", location, match.Highlight(opener, closer))
+	}
+
+	fmt.Println()
+}
+
+// splitList parses a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/stats"
+)
+
+func runStats(args []string) error {
+	statsFlags := flag.NewFlagSet("stats", flag.ExitOnError)
+	all := statsFlags.Bool("all", false, "Report on sessions from all projects")
+	projectPath := statsFlags.String("project", "", "Project path (default: current directory)")
+	since := statsFlags.String("since", "", "Only count messages from this date on (YYYY-MM-DD)")
+	until := statsFlags.String("until", "", "Only count messages up to and including this date (YYYY-MM-DD)")
+	format := statsFlags.String("format", "table", "Output format: table, csv or json")
+	top := statsFlags.Int("top", 10, "Number of most expensive sessions to list (0: all)")
+	output := statsFlags.String("output", "", "Output file (default: stdout)")
+	statsFlags.Parse(args)
+
+	var write func(r *stats.Report, w io.Writer) error
+	switch *format {
+	case "table":
+		write = (*stats.Report).WriteTable
+	case "csv":
+		write = (*stats.Report).WriteCSV
+	case "json":
+		write = (*stats.Report).WriteJSON
+	default:
+		return fmt.Errorf("unknown stats format %q (available: table, csv, json)", *format)
+	}
+
+	var sinceTime, untilTime time.Time
+	var err error
+	if *since != "" {
+		sinceTime, err = time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --since date (use YYYY-MM-DD): %w", err)
+		}
+	}
+	if *until != "" {
+		untilTime, err = time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --until date (use YYYY-MM-DD): %w", err)
+		}
+		// The range includes the whole --until day
+		untilTime = untilTime.AddDate(0, 0, 1)
+	}
+
+	sessions, readers, err := collectSessions(*all, *projectPath)
+	if err != nil {
+		return err
+	}
+
+	aggregator := stats.NewAggregator(sinceTime, untilTime)
+	for _, sessInfo := range sessions {
+		// Skip sessions that ended before or started after the range
+		if !sinceTime.IsZero() && sessInfo.Info.Time.Updated != 0 && sessInfo.Info.GetUpdatedAt().Before(sinceTime) {
+			continue
+		}
+		if !untilTime.IsZero() && sessInfo.Info.Time.Created != 0 && !sessInfo.Info.GetCreatedAt().Before(untilTime) {
+			continue
+		}
+
+		sess, err := readers[sessInfo.SessionID].ReadSession(sessInfo.SessionID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read session %s: %v\n", sessInfo.SessionID, err)
+			continue
+		}
+		aggregator.Add(sess, sessInfo.ProjectName)
+	}
+
+	report := aggregator.Report(*top)
+
+	if *output == "" {
+		return write(report, os.Stdout)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", *output, err)
+	}
+	if err := write(report, file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write stats: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+
+	fmt.Printf("Wrote stats for %d session(s) to %s\n", report.Sessions, *output)
+	return nil
+}
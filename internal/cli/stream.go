@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fantomc0der/opencode-session-export/internal/redact"
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// writeExport renders a session into the file target names, or to stdout when
+// target returns "". Formats implementing render.Streamer are written a
+// message at a time straight from storage, so the session is never loaded
+// whole; other formats, and exports including child sessions, load it first.
+// It returns the file written and the redaction summary.
+func writeExport(reader *session.Reader, renderer render.Renderer, redactor *redact.Redactor, sessionID string, withChildren bool, target func(sess *session.Session) string) (string, string, error) {
+	streamer, ok := renderer.(render.Streamer)
+	if !ok || withChildren {
+		sess, note, err := readSession(reader, redactor, sessionID, withChildren)
+		if err != nil {
+			return "", "", err
+		}
+
+		outputFile := target(sess)
+		if outputFile == "" {
+			if err := renderer.Render(os.Stdout, sess); err != nil {
+				return "", note, fmt.Errorf("failed to render session: %w", err)
+			}
+			return "", note, nil
+		}
+		return outputFile, note, renderToFile(renderer, sess, outputFile)
+	}
+
+	info, err := reader.ReadSessionInfo(sessionID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+
+	var report redact.Report
+	if redactor != nil {
+		if err := redactor.RedactInfo(info, &report); err != nil {
+			return "", "", fmt.Errorf("failed to redact session %s: %w", sessionID, err)
+		}
+	}
+
+	walk := func(fn session.WalkFunc) error {
+		return reader.WalkMessages(sessionID, func(message *session.Message, parts []session.MessagePart) error {
+			if redactor != nil {
+				if err := redactor.RedactMessage(message, &report); err != nil {
+					return fmt.Errorf("failed to redact session %s: %w", sessionID, err)
+				}
+				for i := range parts {
+					if err := redactor.RedactPart(&parts[i], &report); err != nil {
+						return fmt.Errorf("failed to redact session %s: %w", sessionID, err)
+					}
+				}
+			}
+			return fn(message, parts)
+		})
+	}
+
+	outputFile := target(&session.Session{Info: *info})
+	if outputFile == "" {
+		if err := streamer.Stream(os.Stdout, info, walk); err != nil {
+			return "", "", fmt.Errorf("failed to render session: %w", err)
+		}
+		return "", redactionNote(redactor, sessionID, report), nil
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %w", outputFile, err)
+	}
+
+	if err := streamer.Stream(file, info, walk); err != nil {
+		file.Close()
+		return "", "", fmt.Errorf("failed to render session %s: %w", sessionID, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+
+	return outputFile, redactionNote(redactor, sessionID, report), nil
+}
+
+// redactionNote summarizes the substitutions made in a session, or returns ""
+// when redaction is off
+func redactionNote(redactor *redact.Redactor, sessionID string, report redact.Report) string {
+	if redactor == nil {
+		return ""
+	}
+	if report.Total() == 0 {
+		return fmt.Sprintf("Redacted 0 value(s) in session %s", sessionID)
+	}
+	return fmt.Sprintf("Redacted %d value(s) in session %s (%s)", report.Total(), sessionID, report.String())
+}
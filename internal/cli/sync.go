@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/fantomc0der/opencode-session-export/internal/exportsync"
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+func runSync(args []string) error {
+	syncFlags := flag.NewFlagSet("sync", flag.ExitOnError)
+	outputDir := syncFlags.String("output-dir", "", "Directory to keep in sync")
+	all := syncFlags.Bool("all", false, "Sync sessions from all projects")
+	projectPath := syncFlags.String("project", "", "Project path (default: current directory)")
+	format := syncFlags.String("format", "markdown", "Output format ("+strings.Join(render.Names(), ", ")+")")
+	includeCosts := syncFlags.Bool("include-costs", false, "Include cost information")
+	includeTimings := syncFlags.Bool("include-timings", false, "Include timing information")
+	includeSnapshots := syncFlags.Bool("include-snapshots", false, "Include snapshot information")
+	snapshotDiffs := syncFlags.Bool("snapshot-diffs", false, "Diff step changes against the snapshot repository")
+	includeFilesChanged := syncFlags.Bool("include-files-changed", false, "Summarize the lines added and removed per file")
+	reasoning := syncFlags.String("reasoning", "collapse", "How to show model reasoning (show, collapse, omit)")
+	layout := syncFlags.String("layout", "interleaved", "Part order within a message (interleaved, grouped)")
+	stepDividers := syncFlags.Bool("step-dividers", false, "Mark where each step of a message starts")
+	redactSecrets := syncFlags.Bool("redact", false, "Redact secrets and personal data before rendering")
+	redactConfig := syncFlags.String("redact-config", "", "Redaction rules file (implies --redact)")
+	prune := syncFlags.Bool("prune", false, "Delete exports of sessions that no longer exist")
+	syncFlags.Parse(args)
+
+	if *outputDir == "" {
+		return fmt.Errorf("must specify --output-dir <dir>")
+	}
+
+	reasoningMode, err := render.ParseReasoningMode(*reasoning)
+	if err != nil {
+		return err
+	}
+	partLayout, err := render.ParseLayout(*layout)
+	if err != nil {
+		return err
+	}
+
+	renderOptions := render.Options{
+		IncludeCosts:        *includeCosts,
+		IncludeTimings:      *includeTimings,
+		IncludeSnapshots:    *includeSnapshots || *snapshotDiffs,
+		SnapshotDiffs:       *snapshotDiffs,
+		IncludeFilesChanged: *includeFilesChanged,
+		Reasoning:           reasoningMode,
+		Layout:              partLayout,
+		StepDividers:        *stepDividers,
+	}
+	redactor, err := newRedactor(*redactSecrets, *redactConfig)
+	if err != nil {
+		return err
+	}
+
+	// The manifest records renderOptions, so the redactor only goes to the
+	// renderer; the redaction settings are recorded on their own
+	rendererOptions := renderOptions
+	if redactor != nil {
+		rendererOptions.Redactor = redactor
+	}
+	renderer, err := render.New(*format, rendererOptions)
+	if err != nil {
+		return err
+	}
+
+	sessions, readers, err := collectSessions(*all, *projectPath)
+	if err != nil {
+		return err
+	}
+
+	scope := "all"
+	if !*all {
+		if *projectPath == "" {
+			*projectPath = "."
+		}
+		absPath, err := filepath.Abs(*projectPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve project path: %w", err)
+		}
+		scope = "project:" + absPath
+	}
+
+	sources := make([]exportsync.Source, 0, len(sessions))
+	for _, sess := range sessions {
+		sessionID := sess.SessionID
+		reader := readers[sessionID]
+		reader.SetSnapshots(*snapshotDiffs)
+		sources = append(sources, exportsync.Source{
+			SessionID:   sessionID,
+			ProjectName: sess.ProjectName,
+			Updated:     sess.Info.Time.Updated,
+			Load: func() (*session.Session, error) {
+				return loadSession(reader, redactor, sessionID)
+			},
+		})
+	}
+
+	settings := exportsync.Settings{Format: *format, Options: renderOptions}
+	if redactor != nil {
+		settings.Redaction = redactor.Fingerprint()
+	}
+
+	result, err := exportsync.Sync(sources, exportsync.Options{
+		Dir:      *outputDir,
+		Scope:    scope,
+		Settings: settings,
+		Renderer: renderer,
+		Filename: func(sess *session.Session, fullID bool) string {
+			if fullID {
+				return fmt.Sprintf("%s_%s%s", sanitizeFilename(sess.Info.Title), sess.Info.ID, renderer.Extension())
+			}
+			return exportFilename(renderer, sess)
+		},
+		Prune: *prune,
+		Report: func(action exportsync.Action, sessionID, file string) {
+			fmt.Printf("  %-8s %s %s\n", action, sessionID, file)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Synced %s: %d added, %d updated, %d renamed, %d unchanged",
+		*outputDir,
+		result.Actions[exportsync.ActionAdded],
+		result.Actions[exportsync.ActionUpdated],
+		result.Actions[exportsync.ActionRenamed],
+		result.Unchanged)
+	if deleted := result.Actions[exportsync.ActionDeleted]; deleted > 0 {
+		fmt.Printf(", %d deleted (kept; use --prune to remove)", deleted)
+	}
+	if pruned := result.Actions[exportsync.ActionPruned]; pruned > 0 {
+		fmt.Printf(", %d pruned", pruned)
+	}
+	failed := result.Actions[exportsync.ActionFailed]
+	if failed > 0 {
+		fmt.Printf(", %d failed", failed)
+	}
+	fmt.Println()
+
+	if failed > 0 {
+		return fmt.Errorf("%d session(s) failed to sync", failed)
+	}
+	return nil
+}
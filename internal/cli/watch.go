@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/redact"
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+	"github.com/fantomc0der/opencode-session-export/internal/watch"
+)
+
+// watchSessions keeps the exports of the given sessions current until
+// interrupted. A single session goes to outputFile unless outputDir is set.
+func watchSessions(reader *session.Reader, renderer render.Renderer, redactor *redact.Redactor, sessionIDs []string, outputFile, outputDir string) error {
+	if outputFile == "" && outputDir == "" {
+		return fmt.Errorf("--watch needs --output or --output-dir")
+	}
+	if len(sessionIDs) > 1 && outputDir == "" {
+		return fmt.Errorf("--watch with several sessions needs --output-dir")
+	}
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// opencode titles a session after its first exchange, so the file name
+	// is fixed on first write to avoid leaving stale copies behind
+	outputs := make(map[string]string)
+
+	fmt.Fprintf(os.Stderr, "Watching %d session(s); press Ctrl+C to stop\n", len(sessionIDs))
+
+	err := watch.Run(ctx, reader, sessionIDs, watch.Options{
+		Update: func(sess *session.Session) error {
+			if redactor != nil {
+				if _, err := redactor.RedactSession(sess); err != nil {
+					return fmt.Errorf("failed to redact session %s: %w", sess.Info.ID, err)
+				}
+			}
+
+			path, ok := outputs[sess.Info.ID]
+			if !ok {
+				path = outputFile
+				if outputDir != "" {
+					path = filepath.Join(outputDir, exportFilename(renderer, sess))
+				}
+				outputs[sess.Info.ID] = path
+			}
+
+			if err := renderToFileAtomic(renderer, sess, path); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "[%s] Updated %s (%d message(s))\n",
+				time.Now().Format("15:04:05"), path, len(sess.Messages))
+			return nil
+		},
+		Error: func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Stopped watching")
+	return nil
+}
+
+// renderToFileAtomic renders into a temporary file next to outputFile and
+// renames it into place, so readers never see a half-written export
+func renderToFileAtomic(renderer render.Renderer, sess *session.Session, outputFile string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(outputFile), "."+filepath.Base(outputFile)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", outputFile, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := renderer.Render(tmp, sess); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to render session %s: %w", sess.Info.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+
+	// CreateTemp makes the file private; exports get the usual permissions
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputFile, err)
+	}
+
+	if err := os.Rename(tmp.Name(), outputFile); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", outputFile, err)
+	}
+
+	return nil
+}
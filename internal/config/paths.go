@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 )
 
@@ -116,3 +117,55 @@ func sanitizeProjectPath(path string) string {
 
 	return result
 }
+
+// GetProjectID returns the identifier opencode uses for a project in the
+// hash-based storage layout: the SHA of the repository's root commit, or
+// "global" when the path is not inside a git repository with commits
+func GetProjectID(projectPath string) string {
+	gitRoot, err := findGitRoot(projectPath)
+	if err != nil {
+		return "global"
+	}
+
+	cmd := exec.Command("git", "rev-list", "--max-parents=0", "--all")
+	cmd.Dir = gitRoot
+	output, err := cmd.Output()
+	if err != nil {
+		return "global"
+	}
+
+	// opencode picks the lexically smallest root so repositories with
+	// several roots still map to a stable ID
+	var roots []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			roots = append(roots, line)
+		}
+	}
+	if len(roots) == 0 {
+		return "global"
+	}
+	sort.Strings(roots)
+
+	return roots[0]
+}
+
+// GetConfigDir returns the directory holding this tool's own configuration
+// files, such as redaction rules
+func GetConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "opencode-session-export"), nil
+}
+
+// GetCacheDir returns the directory holding this tool's caches, such as the
+// search index. Everything in it can be rebuilt from opencode's storage.
+func GetCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "opencode-session-export"), nil
+}
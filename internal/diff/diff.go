@@ -0,0 +1,259 @@
+// Package diff computes line-based unified diffs, and extracts the file
+// changes recorded by opencode's file editing tools so exports can show them
+// as diffs rather than as raw tool input.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is what a diff does with a line
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Line is one line of a diff
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// contextLines is how many unchanged lines surround each change in a hunk
+const contextLines = 3
+
+// maxTraceSize bounds the memory spent finding a minimal diff, in saved
+// positions. Inputs needing more are diffed as a whole-text replacement.
+const maxTraceSize = 1 << 22
+
+// Lines returns the edits turning lines a into lines b, using Myers'
+// algorithm to find a shortest edit script
+func Lines(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b)-prefix-suffix)
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	return lines
+}
+
+// myers diffs a and b, which have no common prefix or suffix
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		if (len(trace)+1)*len(v) > maxTraceSize {
+			return replace(a, b)
+		}
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Insertion: move down from diagonal k+1
+			} else {
+				x = v[offset+k-1] + 1 // Deletion: move right from diagonal k-1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset)
+			}
+		}
+	}
+
+	return replace(a, b)
+}
+
+// backtrack follows the saved furthest-reaching positions from the end of
+// both inputs back to the start, collecting the edits in reverse
+func backtrack(a, b []string, trace [][]int, offset int) []Line {
+	var reversed []Line
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Insert, b[y-1]})
+			} else {
+				reversed = append(reversed, Line{Delete, a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+// replace deletes every line of a and inserts every line of b
+func replace(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a {
+		lines = append(lines, Line{Delete, text})
+	}
+	for _, text := range b {
+		lines = append(lines, Line{Insert, text})
+	}
+	return lines
+}
+
+// Unified returns a unified diff from oldText to newText under a header
+// naming path, with three lines of context around each change. An empty
+// oldText is shown as a new file. It returns "" if the texts are equal.
+func Unified(path, oldText, newText string) string {
+	lines := Lines(splitLines(oldText), splitLines(newText))
+
+	// oldPos[i] and newPos[i] count the old and new lines before lines[i]
+	oldPos := make([]int, len(lines)+1)
+	newPos := make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if line.Op != Insert {
+			oldPos[i+1]++
+		}
+		if line.Op != Delete {
+			newPos[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// A hunk runs on while changes are close enough to share context
+		start := max(0, i-contextLines)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Op != Equal {
+				end = j + 1
+			} else if j-end >= 2*contextLines {
+				break
+			}
+		}
+		stop := min(len(lines), end+contextLines)
+
+		if out.Len() == 0 {
+			if oldText == "" {
+				out.WriteString("--- /dev/null\n")
+			} else {
+				out.WriteString("--- " + path + "\n")
+			}
+			out.WriteString("+++ " + path + "\n")
+		}
+		out.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldPos[stop]-oldPos[start]),
+			hunkRange(newPos[start], newPos[stop]-newPos[start])))
+
+		for _, line := range lines[start:stop] {
+			switch line.Op {
+			case Equal:
+				out.WriteString(" ")
+			case Delete:
+				out.WriteString("-")
+			case Insert:
+				out.WriteString("+")
+			}
+			out.WriteString(line.Text)
+			out.WriteString("\n")
+		}
+
+		i = stop
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk side. Lines are numbered
+// from one, and an empty side names the line before it.
+func hunkRange(before, count int) string {
+	start := before + 1
+	if count == 0 {
+		start = before
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Count returns the number of lines a unified diff adds and removes. File
+// headers are not counted.
+func Count(unified string) (added, removed int) {
+	lines := strings.Split(unified, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			i++
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,140 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply returns the old and new lines an edit script describes
+func apply(lines []Line) (a, b []string) {
+	for _, line := range lines {
+		if line.Op != Insert {
+			a = append(a, line.Text)
+		}
+		if line.Op != Delete {
+			b = append(b, line.Text)
+		}
+	}
+	return a, b
+}
+
+// edits counts the lines an edit script deletes or inserts
+func edits(lines []Line) int {
+	n := 0
+	for _, line := range lines {
+		if line.Op != Equal {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		want  []Line
+		edits int
+	}{
+		{"both empty", "", "", []Line{}, 0},
+		{"equal", "a b c", "a b c", []Line{{Equal, "a"}, {Equal, "b"}, {Equal, "c"}}, 0},
+		{"all inserted", "", "a b", []Line{{Insert, "a"}, {Insert, "b"}}, 2},
+		{"all deleted", "a b", "", []Line{{Delete, "a"}, {Delete, "b"}}, 2},
+		{"changed middle", "a b c", "a x c", []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "x"}, {Equal, "c"}}, 2},
+		{"inserted middle", "a c", "a b c", []Line{{Equal, "a"}, {Insert, "b"}, {Equal, "c"}}, 1},
+		{"deleted end", "a b c", "a b", []Line{{Equal, "a"}, {Equal, "b"}, {Delete, "c"}}, 1},
+		{"repeated lines", "a a a", "a a", nil, 1},
+		{"moved line", "a b c d", "b c d a", nil, 2},
+		{"classic", "a b c a b b a", "c b a b a c", nil, 5},
+		{"no common lines", "a b c", "x y", nil, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			got := Lines(a, b)
+
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %v, want %v", got, tt.want)
+			}
+			gotA, gotB := apply(got)
+			if strings.Join(gotA, " ") != tt.a || strings.Join(gotB, " ") != tt.b {
+				t.Errorf("Lines() = %v, which turns %q into %q, want %q into %q", got, gotA, gotB, tt.a, tt.b)
+			}
+			if n := edits(got); n != tt.edits {
+				t.Errorf("Lines() makes %d edits, want %d", n, tt.edits)
+			}
+		})
+	}
+}
+
+func TestLinesLargeInput(t *testing.T) {
+	// Inputs with no lines in common need more trace than maxTraceSize
+	// allows, so they fall back to a whole-text replacement
+	a := make([]string, 2000)
+	b := make([]string, 2000)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d", i)
+		b[i] = fmt.Sprintf("b%d", i)
+	}
+	a[1000] = "same"
+	b[1000] = "same"
+
+	got := Lines(a, b)
+	gotA, gotB := apply(got)
+	if !reflect.DeepEqual(gotA, a) || !reflect.DeepEqual(gotB, b) {
+		t.Fatal("Lines() does not turn a into b")
+	}
+	if n := edits(got); n != len(a)+len(b) {
+		t.Errorf("Lines() makes %d edits, want %d", n, len(a)+len(b))
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"new file", "", "a\nb\n", "--- /dev/null\n+++ f.txt\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"deleted content", "a\n", "", "--- f.txt\n+++ f.txt\n@@ -1 +0,0 @@\n-a\n"},
+		{"crlf", "a\r\nb\r\n", "a\nc\n", "--- f.txt\n+++ f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n"},
+		{
+			"context",
+			"1\n2\n3\n4\n5\n6\n7\n8\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n",
+			"--- f.txt\n+++ f.txt\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			"--- f.txt\n+++ f.txt\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			"merged hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n",
+			"one\n2\n3\n4\n5\n6\n7\neight\n",
+			"--- f.txt\n+++ f.txt\n@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("f.txt", tt.old, tt.new); got != tt.want {
+				t.Errorf("Unified()\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	unified := "--- a.txt\n+++ a.txt\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n+d\n--- /dev/null\n+++ b.txt\n@@ -0,0 +1 @@\n+e\n"
+	added, removed := Count(unified)
+	if added != 3 || removed != 1 {
+		t.Errorf("Count() = %d, %d; want 3, 1", added, removed)
+	}
+}
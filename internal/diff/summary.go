@@ -0,0 +1,47 @@
+package diff
+
+// FileStat totals the changes made to one file
+type FileStat struct {
+	Path    string
+	Added   int
+	Removed int
+}
+
+// Summary totals file changes per file, in the order files were first
+// changed. The zero value is an empty summary.
+type Summary struct {
+	files []FileStat
+	index map[string]int
+}
+
+// Add counts changes toward their files' totals
+func (s *Summary) Add(changes ...FileChange) {
+	if s.index == nil {
+		s.index = make(map[string]int)
+	}
+	for _, change := range changes {
+		i, ok := s.index[change.Path]
+		if !ok {
+			i = len(s.files)
+			s.index[change.Path] = i
+			s.files = append(s.files, FileStat{Path: change.Path})
+		}
+		s.files[i].Added += change.Added
+		s.files[i].Removed += change.Removed
+	}
+}
+
+// Files returns the totals of each changed file
+func (s *Summary) Files() []FileStat {
+	return s.files
+}
+
+// Total returns the totals across all files
+func (s *Summary) Total() FileStat {
+	var total FileStat
+	for _, file := range s.files {
+		total.Added += file.Added
+		total.Removed += file.Removed
+	}
+	return total
+}
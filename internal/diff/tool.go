@@ -0,0 +1,187 @@
+package diff
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// FileChange is the change one tool call made to one file
+type FileChange struct {
+	Path    string
+	Diff    string // Unified diff of the change
+	Added   int
+	Removed int
+}
+
+// editInput is the input of the edit tool, and of each edit of multiedit
+type editInput struct {
+	FilePath  string `json:"filePath"`
+	OldString string `json:"oldString"`
+	NewString string `json:"newString"`
+}
+
+// diffMetadata is the metadata edit records, holding the diff it applied
+// with context from the whole file
+type diffMetadata struct {
+	Diff string `json:"diff"`
+}
+
+// ToolChanges returns the file changes recorded by a call to one of
+// opencode's file editing tools: edit, multiedit, write and patch. It returns
+// nil for other tools and for calls whose input cannot be read.
+//
+// Diffs opencode recorded in the tool metadata are preferred, since they have
+// context from the file; otherwise the diff is computed from the input.
+func ToolChanges(tool string, input, metadata json.RawMessage) []FileChange {
+	switch tool {
+	case "edit":
+		var edit editInput
+		if json.Unmarshal(input, &edit) != nil || edit.FilePath == "" {
+			return nil
+		}
+		var recorded diffMetadata
+		json.Unmarshal(metadata, &recorded)
+		return []FileChange{editChange(edit, recorded.Diff)}
+
+	case "multiedit":
+		var multi struct {
+			FilePath string      `json:"filePath"`
+			Edits    []editInput `json:"edits"`
+		}
+		if json.Unmarshal(input, &multi) != nil || multi.FilePath == "" {
+			return nil
+		}
+		var recorded struct {
+			Results []diffMetadata `json:"results"`
+		}
+		json.Unmarshal(metadata, &recorded)
+
+		var changes []FileChange
+		for i, edit := range multi.Edits {
+			edit.FilePath = multi.FilePath
+			recordedDiff := ""
+			if i < len(recorded.Results) {
+				recordedDiff = recorded.Results[i].Diff
+			}
+			changes = append(changes, editChange(edit, recordedDiff))
+		}
+		return changes
+
+	case "write":
+		var write struct {
+			FilePath string `json:"filePath"`
+			Content  string `json:"content"`
+		}
+		if json.Unmarshal(input, &write) != nil || write.FilePath == "" {
+			return nil
+		}
+		return []FileChange{newChange(write.FilePath, Unified(write.FilePath, "", write.Content))}
+
+	case "patch", "apply_patch":
+		var patch struct {
+			PatchText string `json:"patchText"`
+		}
+		if json.Unmarshal(input, &patch) == nil && patch.PatchText != "" {
+			if changes := parsePatch(patch.PatchText); len(changes) > 0 {
+				return changes
+			}
+		}
+		var recorded diffMetadata
+		if json.Unmarshal(metadata, &recorded) == nil && recorded.Diff != "" {
+			return []FileChange{newChange("", cleanDiff(recorded.Diff))}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+func editChange(edit editInput, recordedDiff string) FileChange {
+	if recordedDiff != "" {
+		return newChange(edit.FilePath, cleanDiff(recordedDiff))
+	}
+	if edit.OldString == "" {
+		// Editing with an empty old string creates the file
+		return newChange(edit.FilePath, Unified(edit.FilePath, "", edit.NewString))
+	}
+	return newChange(edit.FilePath, Unified(edit.FilePath, edit.OldString, edit.NewString))
+}
+
+func newChange(path, unified string) FileChange {
+	added, removed := Count(unified)
+	return FileChange{Path: path, Diff: unified, Added: added, Removed: removed}
+}
+
+// cleanDiff drops the "Index:" and separator lines opencode's diffs start
+// with, leaving a plain unified diff
+func cleanDiff(recorded string) string {
+	if i := strings.Index(recorded, "--- "); i > 0 {
+		recorded = recorded[i:]
+	}
+	if !strings.HasSuffix(recorded, "\n") {
+		recorded += "\n"
+	}
+	return recorded
+}
+
+// parsePatch splits the patch tool's input into a unified diff per file. The
+// patch format wraps diff-style hunks in file markers:
+//
+//	*** Begin Patch
+//	*** Update File: path
+//	@@ context
+//	-old
+//	+new
+//	*** Add File: path
+//	+line
+//	*** Delete File: path
+//	*** End Patch
+func parsePatch(text string) []FileChange {
+	var changes []FileChange
+	var path string
+	var body strings.Builder
+	inFile := false
+
+	flush := func() {
+		if inFile {
+			changes = append(changes, newChange(path, body.String()))
+		}
+		body.Reset()
+		inFile = false
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "*** Update File: "):
+			flush()
+			path = strings.TrimPrefix(line, "*** Update File: ")
+			body.WriteString("--- " + path + "\n+++ " + path + "\n")
+			inFile = true
+		case strings.HasPrefix(line, "*** Add File: "):
+			flush()
+			path = strings.TrimPrefix(line, "*** Add File: ")
+			body.WriteString("--- /dev/null\n+++ " + path + "\n")
+			inFile = true
+		case strings.HasPrefix(line, "*** Delete File: "):
+			flush()
+			path = strings.TrimPrefix(line, "*** Delete File: ")
+			body.WriteString("--- " + path + "\n+++ /dev/null\n")
+			inFile = true
+		case strings.HasPrefix(line, "*** Move to: "):
+			// The new name replaces the one in the +++ header
+			moved := strings.TrimPrefix(line, "*** Move to: ")
+			header := strings.Replace(body.String(), "+++ "+path+"\n", "+++ "+moved+"\n", 1)
+			body.Reset()
+			body.WriteString(header)
+			path = moved
+		case strings.HasPrefix(line, "***"):
+			// Begin Patch, End Patch and End of File markers
+		case inFile && (strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "+") ||
+			strings.HasPrefix(line, "-") || strings.HasPrefix(line, " ")):
+			body.WriteString(line + "\n")
+		}
+	}
+	flush()
+
+	return changes
+}
@@ -0,0 +1,347 @@
+// Package exportsync keeps a directory of exports in step with opencode's
+// storage. A manifest in the directory records which file holds each session
+// and what it was rendered from, so a sync only re-renders sessions that
+// changed, renames files when titles change, and notices deleted sessions.
+package exportsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// ManifestName is the manifest file kept in the output directory
+const ManifestName = ".ocse-sync.json"
+
+// ManifestFormat identifies sync manifests
+const ManifestFormat = "opencode-session-export/sync"
+
+// ManifestVersion is incremented when the manifest layout changes
+const ManifestVersion = 1
+
+// Manifest records what a directory was last synced from
+type Manifest struct {
+	Format   string            `json:"format"`
+	Version  int               `json:"version"`
+	Scope    string            `json:"scope"`
+	Settings Settings          `json:"settings"`
+	Sessions map[string]*Entry `json:"sessions"`
+}
+
+// Settings are the render settings of the exports. Changing any of them
+// re-renders every session.
+type Settings struct {
+	Format  string         `json:"format"`
+	Options render.Options `json:"options"`
+	// Redaction fingerprints the redaction rules applied, so changing the
+	// rules re-renders every session too; empty without redaction
+	Redaction string `json:"redaction,omitempty"`
+}
+
+// Entry is the state of one exported session
+type Entry struct {
+	File    string     `json:"file"`
+	Title   string     `json:"title"`
+	Project string     `json:"project,omitempty"`
+	Updated int64      `json:"updated"` // Session Time.Updated the file was rendered from
+	Hash    string     `json:"hash"`    // SHA-256 of the file contents
+	Synced  time.Time  `json:"synced"`
+	Deleted *time.Time `json:"deleted,omitempty"` // When the session was first found missing
+}
+
+// Source is a session that should be exported
+type Source struct {
+	SessionID   string
+	ProjectName string
+	Updated     int64
+	Load        func() (*session.Session, error)
+}
+
+// Options configures a sync
+type Options struct {
+	Dir      string
+	Scope    string // What the sources cover; a directory only syncs one scope
+	Settings Settings
+	Renderer render.Renderer
+	// Filename returns the file name for a session's export. With fullID set
+	// the name must contain the whole session ID, which makes it unique.
+	Filename func(sess *session.Session, fullID bool) string
+	// Prune deletes the exports of sessions that no longer exist instead of
+	// only flagging them in the manifest
+	Prune bool
+	// Report is called for every session that was not left unchanged
+	Report func(action Action, sessionID, file string)
+}
+
+// Action says what a sync did to a session's export
+type Action string
+
+const (
+	ActionAdded   Action = "added"
+	ActionUpdated Action = "updated"
+	ActionRenamed Action = "renamed"
+	ActionDeleted Action = "deleted" // Session is gone; export kept and flagged
+	ActionPruned  Action = "pruned"  // Session is gone; export removed
+	ActionFailed  Action = "failed"
+)
+
+// Result counts the outcome of a sync
+type Result struct {
+	Unchanged int
+	Actions   map[Action]int
+}
+
+// Sync brings opts.Dir up to date with sources
+func Sync(sources []Source, opts Options) (*Result, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	manifest, err := ReadManifest(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		manifest = &Manifest{Scope: opts.Scope, Sessions: make(map[string]*Entry)}
+	} else if manifest.Scope != opts.Scope {
+		return nil, fmt.Errorf("%s was synced from %s, not %s; use a separate directory per scope", opts.Dir, manifest.Scope, opts.Scope)
+	}
+
+	settingsChanged := manifest.Settings != opts.Settings
+	manifest.Format = ManifestFormat
+	manifest.Version = ManifestVersion
+	manifest.Settings = opts.Settings
+
+	s := &syncer{
+		opts:     opts,
+		manifest: manifest,
+		result:   &Result{Actions: make(map[Action]int)},
+	}
+
+	present := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		present[source.SessionID] = true
+		s.syncSession(source, settingsChanged)
+	}
+
+	// Sort so deletions are reported in a stable order
+	var ids []string
+	for id := range manifest.Sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if !present[id] {
+			s.removeSession(id)
+		}
+	}
+
+	if err := writeManifest(opts.Dir, manifest); err != nil {
+		return s.result, err
+	}
+
+	return s.result, nil
+}
+
+type syncer struct {
+	opts     Options
+	manifest *Manifest
+	result   *Result
+}
+
+func (s *syncer) report(action Action, sessionID, file string) {
+	s.result.Actions[action]++
+	if s.opts.Report != nil {
+		s.opts.Report(action, sessionID, file)
+	}
+}
+
+func (s *syncer) syncSession(source Source, settingsChanged bool) {
+	entry, known := s.manifest.Sessions[source.SessionID]
+	if known && entry.Deleted != nil {
+		// The session came back, for instance after an unpack
+		entry.Deleted = nil
+	}
+
+	if known && !settingsChanged && entry.Updated == source.Updated && s.fileIntact(entry) {
+		s.result.Unchanged++
+		return
+	}
+
+	sess, err := source.Load()
+	if err != nil {
+		s.report(ActionFailed, source.SessionID, "")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.opts.Renderer.Render(&buf, sess); err != nil {
+		s.report(ActionFailed, source.SessionID, "")
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	hash := hex.EncodeToString(sum[:])
+
+	file := s.filename(sess, source.SessionID)
+	oldFile := ""
+	if known {
+		oldFile = entry.File
+	}
+
+	if known && file == oldFile && hash == entry.Hash && s.fileIntact(entry) {
+		// Metadata changed without affecting the export
+		entry.Updated = source.Updated
+		entry.Title = sess.Info.Title
+		s.result.Unchanged++
+		return
+	}
+
+	if err := writeFileAtomic(filepath.Join(s.opts.Dir, file), buf.Bytes()); err != nil {
+		s.report(ActionFailed, source.SessionID, file)
+		return
+	}
+
+	action := ActionUpdated
+	switch {
+	case !known:
+		action = ActionAdded
+	case file != oldFile:
+		os.Remove(filepath.Join(s.opts.Dir, oldFile))
+		action = ActionRenamed
+	}
+
+	s.manifest.Sessions[source.SessionID] = &Entry{
+		File:    file,
+		Title:   sess.Info.Title,
+		Project: source.ProjectName,
+		Updated: source.Updated,
+		Hash:    hash,
+		Synced:  time.Now().UTC(),
+	}
+	s.report(action, source.SessionID, file)
+}
+
+func (s *syncer) removeSession(sessionID string) {
+	entry := s.manifest.Sessions[sessionID]
+
+	if s.opts.Prune {
+		err := os.Remove(filepath.Join(s.opts.Dir, entry.File))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.report(ActionFailed, sessionID, entry.File)
+			return
+		}
+		delete(s.manifest.Sessions, sessionID)
+		s.report(ActionPruned, sessionID, entry.File)
+		return
+	}
+
+	if entry.Deleted == nil {
+		now := time.Now().UTC()
+		entry.Deleted = &now
+	}
+	s.report(ActionDeleted, sessionID, entry.File)
+}
+
+// filename picks the export's file name. A session keeps its name while its
+// title is unchanged, and never takes a name another session already holds.
+func (s *syncer) filename(sess *session.Session, sessionID string) string {
+	short := s.opts.Filename(sess, false)
+	full := s.opts.Filename(sess, true)
+
+	if entry, ok := s.manifest.Sessions[sessionID]; ok && (entry.File == short || entry.File == full) {
+		return entry.File
+	}
+	if !s.claimed(short, sessionID) {
+		return short
+	}
+	return full
+}
+
+func (s *syncer) claimed(name, sessionID string) bool {
+	for id, entry := range s.manifest.Sessions {
+		if id != sessionID && entry.File == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fileIntact reports whether the export is still on disk as it was written
+func (s *syncer) fileIntact(entry *Entry) bool {
+	data, err := os.ReadFile(filepath.Join(s.opts.Dir, entry.File))
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == entry.Hash
+}
+
+// ReadManifest loads the manifest of dir, or returns nil if there is none
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse sync manifest: %w", err)
+	}
+	if manifest.Format != ManifestFormat {
+		return nil, fmt.Errorf("%s is not a sync manifest", filepath.Join(dir, ManifestName))
+	}
+	if manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("sync manifest version %d is newer than supported version %d", manifest.Version, ManifestVersion)
+	}
+	if manifest.Sessions == nil {
+		manifest.Sessions = make(map[string]*Entry)
+	}
+
+	return &manifest, nil
+}
+
+func writeManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sync manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ManifestName), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write sync manifest: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
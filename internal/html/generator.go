@@ -0,0 +1,634 @@
+package html
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/diff"
+	"github.com/fantomc0der/opencode-session-export/internal/render"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+	"github.com/fantomc0der/opencode-session-export/internal/snapshot"
+)
+
+func init() {
+	render.Register("html", func(opts render.Options) render.Renderer {
+		return NewGenerator(Options{
+			IncludeCosts:        opts.IncludeCosts,
+			IncludeTimings:      opts.IncludeTimings,
+			IncludeSnapshots:    opts.IncludeSnapshots,
+			SnapshotDiffs:       opts.SnapshotDiffs,
+			IncludeFilesChanged: opts.IncludeFilesChanged,
+			Reasoning:           opts.Reasoning,
+			Layout:              opts.Layout,
+			StepDividers:        opts.StepDividers,
+			Redactor:            opts.Redactor,
+		})
+	})
+}
+
+// Generator handles HTML generation from session data
+type Generator struct {
+	includeCosts        bool
+	includeTimings      bool
+	includeSnapshots    bool
+	snapshotDiffs       bool
+	includeFilesChanged bool
+	reasoning           render.ReasoningMode
+	layout              render.Layout
+	stepDividers        bool
+	redactor            render.TextRedactor
+}
+
+// Options configures the HTML generator
+type Options struct {
+	IncludeCosts        bool
+	IncludeTimings      bool
+	IncludeSnapshots    bool
+	SnapshotDiffs       bool                 // Diff patches against SessionInfo.SnapshotDir
+	IncludeFilesChanged bool                 // Summarize the files edited in the session
+	Reasoning           render.ReasoningMode // Default: render.ReasoningCollapse
+	Layout              render.Layout        // Default: render.LayoutInterleaved
+	StepDividers        bool                 // Mark where each step of a message starts
+	Redactor            render.TextRedactor  // Scrubs snapshot diffs, when set
+}
+
+// NewGenerator creates a new HTML generator
+func NewGenerator(opts Options) *Generator {
+	return &Generator{
+		includeCosts:        opts.IncludeCosts,
+		includeTimings:      opts.IncludeTimings,
+		includeSnapshots:    opts.IncludeSnapshots,
+		snapshotDiffs:       opts.SnapshotDiffs,
+		includeFilesChanged: opts.IncludeFilesChanged,
+		reasoning:           opts.Reasoning,
+		layout:              opts.Layout,
+		stepDividers:        opts.StepDividers,
+		redactor:            opts.Redactor,
+	}
+}
+
+// document is the state of rendering one session. It is kept apart from the
+// Generator, which may render several sessions at once.
+type document struct {
+	children  *render.Children
+	files     diff.Summary
+	totals    render.Totals
+	snapshots *snapshot.Repo // Resolves patch diffs, when enabled and available
+}
+
+// Render writes the HTML for a session to w
+func (g *Generator) Render(w io.Writer, sess *session.Session) error {
+	content, err := g.Generate(sess)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, content)
+	return err
+}
+
+// Extension returns the file extension for HTML exports
+func (g *Generator) Extension() string {
+	return ".html"
+}
+
+// MIMEType returns the media type of HTML exports
+func (g *Generator) MIMEType() string {
+	return "text/html; charset=utf-8"
+}
+
+// Generate creates a self-contained HTML document from a session
+func (g *Generator) Generate(sess *session.Session) (string, error) {
+	var out strings.Builder
+
+	out.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	out.WriteString("<meta charset=\"utf-8\">\n")
+	out.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	out.WriteString(fmt.Sprintf("<title>%s</title>\n", escape(sess.Info.Title)))
+	out.WriteString("<style>\n")
+	out.WriteString(stylesheet)
+	out.WriteString("</style>\n")
+	out.WriteString("<script>\n")
+	out.WriteString(themeScript)
+	out.WriteString("</script>\n")
+	out.WriteString("</head>\n<body>\n")
+	out.WriteString("<button class=\"theme-toggle\" type=\"button\" onclick=\"toggleTheme()\" title=\"Toggle dark/light theme\">&#9681;</button>\n")
+	out.WriteString("<main>\n")
+
+	g.writeSession(&out, sess, "")
+
+	out.WriteString("</main>\n</body>\n</html>\n")
+
+	return out.String(), nil
+}
+
+// writeSession writes a session's header and messages. Child sessions are
+// nested under the task calls that started them; anchor prefixes the
+// message IDs so those of nested sessions stay unique.
+func (g *Generator) writeSession(out *strings.Builder, sess *session.Session, anchor string) {
+	g.writeSessionHeader(out, &sess.Info)
+
+	doc := &document{children: render.NewChildren(sess.Children)}
+	if g.includeSnapshots && g.snapshotDiffs {
+		doc.snapshots = snapshot.Open(sess.Info.SnapshotDir)
+	}
+	partsByMessage := g.groupPartsByMessage(sess.Parts)
+	for i, message := range sess.Messages {
+		g.writeMessage(out, &message, partsByMessage[message.ID], i+1, anchor, doc)
+	}
+
+	if g.includeCosts {
+		g.writeTotals(out, &doc.totals)
+	}
+
+	if g.includeFilesChanged {
+		g.writeFilesChanged(out, &doc.files)
+	}
+
+	// Child sessions no task call in the transcript refers to
+	if remaining := doc.children.Remaining(); len(remaining) > 0 {
+		out.WriteString("<h2>Child Sessions</h2>\n")
+		for _, child := range remaining {
+			g.writeChildSession(out, child)
+		}
+	}
+}
+
+func (g *Generator) writeChildSession(out *strings.Builder, child *session.Session) {
+	out.WriteString(fmt.Sprintf("<section class=\"child-session\" id=\"%s\">\n", escape(child.Info.ID)))
+	g.writeSession(out, child, child.Info.ID+"-")
+	out.WriteString("</section>\n")
+}
+
+func (g *Generator) writeSessionHeader(out *strings.Builder, info *session.SessionInfo) {
+	out.WriteString("<header class=\"session-header\">\n")
+	out.WriteString(fmt.Sprintf("<h1>%s</h1>\n", escape(info.Title)))
+	out.WriteString("<dl>\n")
+	out.WriteString(fmt.Sprintf("<dt>Session ID</dt><dd><code>%s</code></dd>\n", escape(info.ID)))
+	out.WriteString(fmt.Sprintf("<dt>Created</dt><dd>%s</dd>\n", info.GetCreatedAt().Format("2006-01-02 15:04:05")))
+
+	createdAt := info.GetCreatedAt()
+	updatedAt := info.GetUpdatedAt()
+	if !updatedAt.IsZero() && !updatedAt.Equal(createdAt) {
+		out.WriteString(fmt.Sprintf("<dt>Duration</dt><dd>%s</dd>\n", formatDuration(updatedAt.Sub(createdAt))))
+	}
+
+	if info.ShareURL != nil {
+		url := escape(*info.ShareURL)
+		out.WriteString(fmt.Sprintf("<dt>Share URL</dt><dd><a href=\"%s\">%s</a></dd>\n", url, url))
+	}
+
+	out.WriteString("</dl>\n</header>\n")
+}
+
+func (g *Generator) writeMessage(out *strings.Builder, msg *session.Message, parts []session.MessagePart, messageNum int, anchor string, doc *document) {
+	out.WriteString(fmt.Sprintf("<section class=\"message %s\" id=\"%smessage-%d\">\n", escape(msg.Role), escape(anchor), messageNum))
+	out.WriteString("<div class=\"message-header\">\n")
+	out.WriteString(fmt.Sprintf("<h2>Message %d: %s</h2>\n", messageNum, escape(strings.Title(msg.Role))))
+
+	out.WriteString("<div class=\"meta\">")
+	out.WriteString(fmt.Sprintf("<span>%s</span>", msg.GetCreatedAt().Format("15:04:05")))
+
+	var usage session.Usage
+	if msg.Role == "assistant" {
+		usage = session.MessageUsage(msg, parts)
+		doc.totals.Add(msg, usage)
+
+		if msg.Model != nil {
+			out.WriteString(fmt.Sprintf("<span>Model: %s</span>", escape(*msg.Model)))
+		}
+		if g.includeCosts && msg.Cost != nil {
+			out.WriteString(fmt.Sprintf("<span>Cost: $%.4f</span>", *msg.Cost))
+		} else if g.includeCosts && usage.Steps > 0 {
+			out.WriteString(fmt.Sprintf("<span>Cost: $%.4f</span>", usage.Cost))
+		}
+		if msg.Tokens != nil {
+			out.WriteString(fmt.Sprintf("<span>Tokens: %s</span>", escape(render.FormatTokens(*msg.Tokens))))
+		}
+	}
+
+	out.WriteString("</div>\n</div>\n")
+
+	if msg.Error != nil {
+		out.WriteString(fmt.Sprintf("<div class=\"message-error\">⚠️ <strong>Error:</strong> %s</div>\n", escape(msg.Error.Description())))
+	}
+
+	g.writeParts(out, msg, parts, doc)
+
+	// A single step's usage is the message's, and was written with the step
+	if g.includeCosts && usage.Steps > 1 {
+		out.WriteString(fmt.Sprintf("<div class=\"usage message-usage\">Message total: %d steps, %s · $%.4f</div>\n",
+			usage.Steps, render.FormatTokens(usage.Tokens), usage.Cost))
+	}
+
+	out.WriteString("</section>\n")
+}
+
+func (g *Generator) writeParts(out *strings.Builder, msg *session.Message, parts []session.MessagePart, doc *document) {
+	layout := render.PartLayout{
+		Layout:       g.layout,
+		Reasoning:    g.reasoning,
+		StepDividers: g.stepDividers,
+		Snapshots:    g.includeSnapshots,
+	}
+	// Writing to a strings.Builder cannot fail
+	layout.Write(&partWriter{g: g, out: out, doc: doc}, msg, parts)
+}
+
+// partWriter writes the parts of a message as HTML
+type partWriter struct {
+	g   *Generator
+	out *strings.Builder
+	doc *document
+}
+
+func (w *partWriter) Text(part *session.MessagePart) {
+	w.g.writeTextPart(w.out, *part)
+}
+
+func (w *partWriter) Reasoning(part *session.MessagePart, tokens int) {
+	w.g.writeReasoningPart(w.out, *part, tokens)
+}
+
+func (w *partWriter) Tool(part *session.MessagePart) error {
+	w.g.writeToolCall(w.out, *part, w.doc)
+	return nil
+}
+
+func (w *partWriter) StartAttachments(heading bool) {
+	if heading {
+		w.out.WriteString("<h3>Attachments</h3>\n")
+	}
+	w.out.WriteString("<ul class=\"attachments\">\n")
+}
+
+func (w *partWriter) Attachment(part *session.MessagePart) {
+	w.g.writeFilePart(w.out, *part)
+}
+
+func (w *partWriter) EndAttachments() {
+	w.out.WriteString("</ul>\n")
+}
+
+func (w *partWriter) StartTools() {
+	w.out.WriteString("<h3>Tool Executions</h3>\n")
+}
+
+func (w *partWriter) StepDivider(n int) {
+	w.out.WriteString(fmt.Sprintf("<div class=\"step-divider\">Step %d</div>\n", n))
+}
+
+// StepFinish writes the snapshots a step recorded and its tokens and cost,
+// as far as the snapshot and cost options include them
+func (w *partWriter) StepFinish(step render.Step) {
+	if snapshots := step.Snapshots(code); w.g.includeSnapshots && snapshots != "" {
+		w.out.WriteString(fmt.Sprintf("<div class=\"snapshots\">📸 Step %d snapshots: %s</div>\n", step.N, snapshots))
+	}
+	if w.g.includeCosts {
+		w.out.WriteString(fmt.Sprintf("<div class=\"usage step-usage\">Step %d: %s · $%.4f</div>\n",
+			step.N, render.FormatTokens(step.Finish.Tokens), step.Finish.Cost))
+	}
+}
+
+func (w *partWriter) Snapshot(hash string) {
+	w.out.WriteString(fmt.Sprintf("<div class=\"snapshots\">📸 Snapshot: %s</div>\n", code(snapshot.Short(hash))))
+}
+
+// Patch lists the files a step changed, followed by their diff when the
+// session's snapshot repository is available to resolve it
+func (w *partWriter) Patch(patch *session.PatchPart, end string) {
+	w.out.WriteString("<details class=\"patch\">\n")
+	w.out.WriteString(fmt.Sprintf("<summary>Patch: %d file(s) changed since snapshot %s</summary>\n",
+		len(patch.Files), code(snapshot.Short(patch.Hash))))
+	w.out.WriteString("<ul>\n")
+	for _, file := range patch.Files {
+		w.out.WriteString(fmt.Sprintf("<li>%s</li>\n", code(file)))
+	}
+	w.out.WriteString("</ul>\n")
+
+	changes, err := render.PatchDiff(w.doc.snapshots, patch, end, w.g.redactor)
+	if err != nil {
+		w.out.WriteString(fmt.Sprintf("<p class=\"error\">Could not resolve snapshot diff: %s</p>\n", escape(err.Error())))
+	} else if changes != "" {
+		writeCodeBlock(w.out, strings.TrimSuffix(changes, "\n"), "diff")
+	}
+
+	w.out.WriteString("</details>\n")
+}
+
+func (w *partWriter) Other(part *session.MessagePart) {
+	w.g.writeOtherPart(w.out, *part)
+}
+
+// code formats text as inline code
+func code(text string) string {
+	return "<code>" + escape(text) + "</code>"
+}
+
+// writeToolCall writes a tool part followed by the child session it started,
+// if the export includes it
+func (g *Generator) writeToolCall(out *strings.Builder, part session.MessagePart, doc *document) {
+	g.writeToolPart(out, part, doc)
+
+	if child := doc.children.Take(session.TaskSessionID(&part)); child != nil {
+		g.writeChildSession(out, child)
+	}
+}
+
+func (g *Generator) writeTextPart(out *strings.Builder, part session.MessagePart) {
+	text := ""
+	if part.Text != nil {
+		text = *part.Text
+	} else {
+		var textData session.TextPartData
+		if err := json.Unmarshal(part.Data, &textData); err != nil {
+			out.WriteString(fmt.Sprintf("<p class=\"error\">Error parsing text part: %s</p>\n", escape(err.Error())))
+			return
+		}
+		text = textData.Text
+	}
+
+	out.WriteString("<div class=\"text\">\n")
+	out.WriteString(renderMarkdown(text))
+	out.WriteString("</div>\n")
+}
+
+// writeReasoningPart writes the model's reasoning folded in a <details>
+// element, or as a block quote when it is shown in full
+func (g *Generator) writeReasoningPart(out *strings.Builder, part session.MessagePart, tokens int) {
+	text := strings.TrimSpace(session.ReasoningText(&part))
+	if text == "" {
+		return
+	}
+
+	label := "💭 Reasoning"
+	if tokens > 0 {
+		label += fmt.Sprintf(" <span class=\"duration\">%d tokens</span>", tokens)
+	}
+	if g.includeTimings && part.Time != nil && part.Time.End > part.Time.Start {
+		duration := time.UnixMilli(part.Time.End).Sub(time.UnixMilli(part.Time.Start))
+		label += fmt.Sprintf(" <span class=\"duration\">%s</span>", formatDuration(duration))
+	}
+
+	if g.reasoning == render.ReasoningShow {
+		out.WriteString(fmt.Sprintf("<blockquote class=\"reasoning\">\n<p><strong>%s</strong></p>\n", label))
+		out.WriteString(renderMarkdown(text))
+		out.WriteString("</blockquote>\n")
+		return
+	}
+
+	out.WriteString(fmt.Sprintf("<details class=\"reasoning\">\n<summary>%s</summary>\n<div class=\"text\">\n", label))
+	out.WriteString(renderMarkdown(text))
+	out.WriteString("</div>\n</details>\n")
+}
+
+func (g *Generator) writeToolPart(out *strings.Builder, part session.MessagePart, doc *document) {
+	var toolData session.ToolPartData
+
+	// For tool parts, the data is directly in the part fields
+	if part.Tool != nil && part.State != nil {
+		toolData.Tool = *part.Tool
+		if err := json.Unmarshal(part.State, &toolData.State); err != nil {
+			out.WriteString(fmt.Sprintf("<p class=\"error\">Error parsing tool state: %s</p>\n", escape(err.Error())))
+			return
+		}
+	} else if err := json.Unmarshal(part.Data, &toolData); err != nil {
+		out.WriteString(fmt.Sprintf("<p class=\"error\">Error parsing tool part: %s</p>\n", escape(err.Error())))
+		return
+	}
+
+	state := toolData.State
+	statusIcon := getStatusIcon(state.Status)
+
+	out.WriteString(fmt.Sprintf("<details class=\"tool status-%s\">\n<summary>", escape(state.Status)))
+	out.WriteString(fmt.Sprintf("%s <strong>%s</strong>", statusIcon, escape(toolData.Tool)))
+	if state.Title != nil {
+		out.WriteString(fmt.Sprintf(" <span class=\"tool-title\">%s</span>", escape(*state.Title)))
+	}
+	if g.includeTimings && state.Time != nil {
+		duration := time.UnixMilli(state.Time.End).Sub(time.UnixMilli(state.Time.Start))
+		out.WriteString(fmt.Sprintf(" <span class=\"duration\">%s</span>", formatDuration(duration)))
+	}
+	out.WriteString("</summary>\n")
+
+	out.WriteString(fmt.Sprintf("<p class=\"status\">Status: %s %s</p>\n", statusIcon, escape(strings.Title(state.Status))))
+
+	if changes := diff.ToolChanges(toolData.Tool, state.Input, state.Metadata); len(changes) > 0 {
+		g.writeFileChanges(out, changes)
+		if state.Status == "completed" {
+			doc.files.Add(changes...)
+		}
+	} else if state.Input != nil {
+		out.WriteString("<h4>Input</h4>\n")
+		g.writeInputBlock(out, state.Input, toolData.Tool)
+	}
+
+	if state.Output != nil {
+		out.WriteString("<h4>Output</h4>\n")
+		if outputStr, ok := state.Output.(string); ok {
+			writeCodeBlock(out, outputStr, "")
+		} else if outputJSON, err := json.MarshalIndent(state.Output, "", "  "); err == nil {
+			writeCodeBlock(out, string(outputJSON), "json")
+		} else {
+			writeCodeBlock(out, fmt.Sprintf("%v", state.Output), "")
+		}
+	}
+
+	out.WriteString("</details>\n")
+}
+
+func (g *Generator) writeInputBlock(out *strings.Builder, data json.RawMessage, toolName string) {
+	// Bash commands read better as a script than as a JSON object
+	if toolName == "bash" || toolName == "shell" {
+		var input struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal(data, &input); err == nil && input.Command != "" {
+			writeCodeBlock(out, input.Command, "bash")
+			return
+		}
+	}
+
+	var prettyData interface{}
+	if err := json.Unmarshal(data, &prettyData); err == nil {
+		if prettyJSON, err := json.MarshalIndent(prettyData, "", "  "); err == nil {
+			writeCodeBlock(out, string(prettyJSON), "json")
+			return
+		}
+	}
+
+	writeCodeBlock(out, string(data), "")
+}
+
+// writeFileChanges writes the diff of each file a tool call changed
+func (g *Generator) writeFileChanges(out *strings.Builder, changes []diff.FileChange) {
+	for _, change := range changes {
+		if change.Path != "" {
+			out.WriteString(fmt.Sprintf("<h4><code>%s</code> <span class=\"added\">+%d</span> <span class=\"removed\">-%d</span></h4>\n",
+				escape(change.Path), change.Added, change.Removed))
+		}
+		writeCodeBlock(out, strings.TrimSuffix(change.Diff, "\n"), "diff")
+	}
+}
+
+// writeFilesChanged writes the lines added and removed per file over the
+// whole session
+func (g *Generator) writeFilesChanged(out *strings.Builder, files *diff.Summary) {
+	if len(files.Files()) == 0 {
+		return
+	}
+
+	out.WriteString("<section class=\"files-changed\">\n<h2>Files Changed</h2>\n<table>\n")
+	out.WriteString("<tr><th>File</th><th>Added</th><th>Removed</th></tr>\n")
+	for _, file := range files.Files() {
+		path := file.Path
+		if path == "" {
+			path = "(unknown)"
+		}
+		out.WriteString(fmt.Sprintf("<tr><td><code>%s</code></td><td class=\"added\">+%d</td><td class=\"removed\">-%d</td></tr>\n",
+			escape(path), file.Added, file.Removed))
+	}
+	total := files.Total()
+	out.WriteString(fmt.Sprintf("<tr><th>Total: %d file(s)</th><th class=\"added\">+%d</th><th class=\"removed\">-%d</th></tr>\n",
+		len(files.Files()), total.Added, total.Removed))
+	out.WriteString("</table>\n</section>\n")
+}
+
+// writeTotals writes the session's token and cost totals, and how they
+// compare with the costs messages report for themselves
+func (g *Generator) writeTotals(out *strings.Builder, totals *render.Totals) {
+	if totals.Messages == 0 {
+		return
+	}
+
+	out.WriteString("<section class=\"session-totals\">\n<h2>Session Totals</h2>\n<table>\n")
+	writeTableRow(out, "th", render.TotalsColumns)
+	writeTableRow(out, "td", totals.Row())
+	out.WriteString("</table>\n")
+
+	if note, ok := totals.CostNote(); note != "" && ok {
+		out.WriteString(fmt.Sprintf("<p>%s</p>\n", escape(note)))
+	} else if note != "" {
+		out.WriteString(fmt.Sprintf("<p class=\"error\">%s</p>\n", escape(note)))
+	}
+
+	out.WriteString("</section>\n")
+}
+
+// writeTableRow writes cells as a table row of cell elements
+func writeTableRow(out *strings.Builder, cell string, cells []string) {
+	out.WriteString("<tr>")
+	for _, text := range cells {
+		out.WriteString(fmt.Sprintf("<%s>%s</%s>", cell, escape(text), cell))
+	}
+	out.WriteString("</tr>\n")
+}
+
+func (g *Generator) writeFilePart(out *strings.Builder, part session.MessagePart) {
+	var fileData session.FilePartData
+	if err := json.Unmarshal(part.Data, &fileData); err != nil {
+		out.WriteString(fmt.Sprintf("<li class=\"error\">Error parsing file part: %s</li>\n", escape(err.Error())))
+		return
+	}
+
+	out.WriteString(fmt.Sprintf("<li>%s <code>%s</code>", getFileIcon(fileData.MimeType), escape(fileData.Name)))
+	if fileData.Size != nil {
+		out.WriteString(fmt.Sprintf(" (%s)", formatFileSize(*fileData.Size)))
+	}
+	out.WriteString(fmt.Sprintf(" (%s)</li>\n", escape(fileData.MimeType)))
+}
+
+func (g *Generator) writeOtherPart(out *strings.Builder, part session.MessagePart) {
+	out.WriteString(fmt.Sprintf("<details class=\"other-part\">\n<summary>%s Part</summary>\n", escape(strings.Title(part.Type))))
+
+	var prettyData interface{}
+	if err := json.Unmarshal(part.Data, &prettyData); err == nil {
+		if prettyJSON, err := json.MarshalIndent(prettyData, "", "  "); err == nil {
+			writeCodeBlock(out, string(prettyJSON), "json")
+			out.WriteString("</details>\n")
+			return
+		}
+	}
+
+	writeCodeBlock(out, string(part.Data), "")
+	out.WriteString("</details>\n")
+}
+
+func (g *Generator) groupPartsByMessage(parts []session.MessagePart) map[string][]session.MessagePart {
+	result := make(map[string][]session.MessagePart)
+	for _, part := range parts {
+		result[part.MessageID] = append(result[part.MessageID], part)
+	}
+	return result
+}
+
+// writeCodeBlock writes a highlighted <pre> block for the given language
+func writeCodeBlock(out *strings.Builder, code, lang string) {
+	if lang != "" {
+		out.WriteString(fmt.Sprintf("<pre class=\"code\" data-lang=\"%s\"><code>", escape(lang)))
+	} else {
+		out.WriteString("<pre class=\"code\"><code>")
+	}
+	out.WriteString(highlight(code, lang))
+	out.WriteString("</code></pre>\n")
+}
+
+func escape(s string) string {
+	return template.HTMLEscapeString(s)
+}
+
+func getStatusIcon(state string) string {
+	switch state {
+	case "completed":
+		return "✅"
+	case "error":
+		return "❌"
+	case "running":
+		return "🔄"
+	case "pending":
+		return "⏳"
+	default:
+		return "❓"
+	}
+}
+
+func getFileIcon(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "🖼️"
+	case strings.HasPrefix(mimeType, "text/"):
+		return "📄"
+	case strings.Contains(mimeType, "json"):
+		return "📋"
+	case strings.Contains(mimeType, "pdf"):
+		return "📕"
+	default:
+		return "📎"
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%.1fm", d.Minutes())
+	}
+	return fmt.Sprintf("%.1fh", d.Hours())
+}
+
+func formatFileSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,295 @@
+package html
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// numberPattern matches decimal, float and hex literals but not tokens like 001.sql
+var numberPattern = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|\d+(\.\d+)?([eE][+-]?\d+)?)$`)
+
+// languageSpec describes the lexical rules the highlighter needs for a language
+type languageSpec struct {
+	lineComments  []string
+	blockComment  [2]string
+	quotes        string
+	keywords      map[string]bool
+	literals      map[string]bool
+	highlightKeys bool // color "key": pairs (JSON-like languages)
+}
+
+var languageSpecs = map[string]*languageSpec{
+	"json": {
+		quotes:        `"`,
+		literals:      wordSet("true false null"),
+		highlightKeys: true,
+	},
+	"bash": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     wordSet("if then else elif fi for while until do done case esac in function return local export set unset source exit break continue sudo cd echo"),
+		literals:     wordSet("true false"),
+	},
+	"go": {
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords:     wordSet("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var"),
+		literals:     wordSet("true false nil iota"),
+	},
+	"javascript": {
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+		keywords:     wordSet("async await break case catch class const continue default delete do else export extends finally for from function if import in instanceof interface let new of return switch throw try type typeof var void while yield"),
+		literals:     wordSet("true false null undefined this"),
+	},
+	"python": {
+		lineComments: []string{"#"},
+		quotes:       `"'`,
+		keywords:     wordSet("and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield"),
+		literals:     wordSet("True False None self"),
+	},
+	"rust": {
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"`,
+		keywords:     wordSet("as async await break const continue crate else enum extern fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe use where while"),
+		literals:     wordSet("true false None Some Ok Err"),
+	},
+	"yaml": {
+		lineComments:  []string{"#"},
+		quotes:        `"'`,
+		literals:      wordSet("true false null yes no"),
+		highlightKeys: true,
+	},
+	"sql": {
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		keywords:     wordSet("select from where insert into update delete create table drop alter join left right inner outer on group by order having limit offset values set and or not as distinct union SELECT FROM WHERE INSERT INTO UPDATE DELETE CREATE TABLE DROP ALTER JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT OFFSET VALUES SET AND OR NOT AS DISTINCT UNION"),
+		literals:     wordSet("null NULL true false TRUE FALSE"),
+	},
+}
+
+var languageAliases = map[string]string{
+	"sh":         "bash",
+	"shell":      "bash",
+	"zsh":        "bash",
+	"console":    "bash",
+	"golang":     "go",
+	"js":         "javascript",
+	"jsx":        "javascript",
+	"ts":         "javascript",
+	"tsx":        "javascript",
+	"typescript": "javascript",
+	"py":         "python",
+	"rs":         "rust",
+	"yml":        "yaml",
+	"jsonc":      "json",
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+func lookupLanguage(lang string) *languageSpec {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := languageAliases[lang]; ok {
+		lang = alias
+	}
+	return languageSpecs[lang]
+}
+
+// highlight returns HTML-escaped code with token spans for known languages.
+// Unknown languages are escaped without any markup.
+func highlight(code, lang string) string {
+	if strings.EqualFold(strings.TrimSpace(lang), "diff") {
+		return highlightDiff(code)
+	}
+
+	spec := lookupLanguage(lang)
+	if spec == nil {
+		return escape(code)
+	}
+
+	var out strings.Builder
+	src := []rune(code)
+	n := len(src)
+
+	for i := 0; i < n; {
+		if comment := spec.matchComment(src, i); comment > 0 {
+			writeToken(&out, "tok-comment", string(src[i:i+comment]))
+			i += comment
+			continue
+		}
+
+		r := src[i]
+
+		if strings.ContainsRune(spec.quotes, r) {
+			end := scanString(src, i)
+			class := "tok-string"
+			if spec.highlightKeys && nextNonSpace(src, end) == ':' {
+				class = "tok-key"
+			}
+			writeToken(&out, class, string(src[i:end]))
+			i = end
+			continue
+		}
+
+		if unicode.IsDigit(r) && (i == 0 || !isIdentRune(src[i-1])) {
+			end := i
+			for end < n && (isIdentRune(src[end]) || src[end] == '.') {
+				end++
+			}
+			if word := string(src[i:end]); numberPattern.MatchString(word) {
+				writeToken(&out, "tok-number", word)
+			} else {
+				out.WriteString(escape(word))
+			}
+			i = end
+			continue
+		}
+
+		if isIdentRune(r) {
+			end := i
+			for end < n && isIdentRune(src[end]) {
+				end++
+			}
+			word := string(src[i:end])
+			switch {
+			case spec.keywords[word]:
+				writeToken(&out, "tok-keyword", word)
+			case spec.literals[word]:
+				writeToken(&out, "tok-literal", word)
+			case spec.highlightKeys && nextNonSpace(src, end) == ':' && atLineStart(src, i):
+				writeToken(&out, "tok-key", word)
+			default:
+				out.WriteString(escape(word))
+			}
+			i = end
+			continue
+		}
+
+		out.WriteString(escape(string(r)))
+		i++
+	}
+
+	return out.String()
+}
+
+// matchComment returns the length of the comment starting at i, or 0
+func (s *languageSpec) matchComment(src []rune, i int) int {
+	for _, prefix := range s.lineComments {
+		if hasPrefixAt(src, i, prefix) {
+			// "#" only starts a comment at a word boundary, so ${#var} and URLs stay intact
+			if prefix == "#" && i > 0 && !unicode.IsSpace(src[i-1]) {
+				return 0
+			}
+			end := i
+			for end < len(src) && src[end] != '\n' {
+				end++
+			}
+			return end - i
+		}
+	}
+
+	opener, closer := s.blockComment[0], s.blockComment[1]
+	if opener != "" && hasPrefixAt(src, i, opener) {
+		for end := i + len(opener); end < len(src); end++ {
+			if hasPrefixAt(src, end, closer) {
+				return end + len(closer) - i
+			}
+		}
+		return len(src) - i
+	}
+
+	return 0
+}
+
+func scanString(src []rune, start int) int {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i + 1
+		case '\n':
+			// Unterminated single-line strings stop at the end of the line
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(src)
+}
+
+func writeToken(out *strings.Builder, class, text string) {
+	out.WriteString("<span class=\"")
+	out.WriteString(class)
+	out.WriteString("\">")
+	out.WriteString(escape(text))
+	out.WriteString("</span>")
+}
+
+func hasPrefixAt(src []rune, i int, prefix string) bool {
+	for _, r := range prefix {
+		if i >= len(src) || src[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func nextNonSpace(src []rune, i int) rune {
+	for ; i < len(src); i++ {
+		if src[i] != ' ' && src[i] != '\t' {
+			return src[i]
+		}
+	}
+	return 0
+}
+
+// atLineStart reports whether only indentation or a list dash precedes i on its line
+func atLineStart(src []rune, i int) bool {
+	for j := i - 1; j >= 0 && src[j] != '\n'; j-- {
+		if src[j] != ' ' && src[j] != '\t' && src[j] != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// highlightDiff marks the added, removed and hunk header lines of a unified
+// diff. Diffs are line-oriented, so they skip the tokenizer.
+func highlightDiff(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			lines[i] = "<span class=\"tok-keyword\">" + escape(line) + "</span>"
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = "<span class=\"tok-hunk\">" + escape(line) + "</span>"
+		case strings.HasPrefix(line, "+"):
+			lines[i] = "<span class=\"tok-inserted\">" + escape(line) + "</span>"
+		case strings.HasPrefix(line, "-"):
+			lines[i] = "<span class=\"tok-deleted\">" + escape(line) + "</span>"
+		default:
+			lines[i] = escape(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
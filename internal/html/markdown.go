@@ -0,0 +1,196 @@
+package html
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	orderedItemPattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	bulletItemPattern  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	rulePattern        = regexp.MustCompile(`^\s*([-*_])(\s*([-*_]))*\s*$`)
+	// Matches **bold** or __bold__ spans
+	boldPattern = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	// Matches *italic* or _italic_ that is not part of a word like snake_case
+	italicPattern = regexp.MustCompile(`(^|[^\w*])\*([^*\s][^*]*)\*|(^|[^\w_])_([^_\s][^_]*)_`)
+	// Matches [label](url) links after escaping, so the URL may contain &amp; entities
+	linkPattern = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// renderMarkdown converts the markdown subset assistants typically produce
+// (headings, lists, quotes, fenced code and inline emphasis) into HTML.
+// Anything it does not recognise is emitted as an escaped paragraph.
+func renderMarkdown(text string) string {
+	var out strings.Builder
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var paragraph []string
+	flushParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		out.WriteString("<p>")
+		out.WriteString(renderInline(strings.Join(paragraph, "\n")))
+		out.WriteString("</p>\n")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flushParagraph()
+			fence := fenceRun(trimmed)
+			lang := strings.TrimSpace(trimmed[len(fence):])
+			var code []string
+			for i++; i < len(lines); i++ {
+				if closesFence(strings.TrimSpace(lines[i]), fence) {
+					break
+				}
+				code = append(code, lines[i])
+			}
+			writeCodeBlock(&out, strings.Join(code, "\n"), lang)
+
+		case trimmed == "":
+			flushParagraph()
+
+		case headingPattern.MatchString(trimmed):
+			flushParagraph()
+			match := headingPattern.FindStringSubmatch(trimmed)
+			// Transcript headings are nested under message headings, so demote by two levels
+			level := len(match[1]) + 2
+			if level > 6 {
+				level = 6
+			}
+			tag := string(rune('0' + level))
+			out.WriteString("<h" + tag + ">" + renderInline(match[2]) + "</h" + tag + ">\n")
+
+		case rulePattern.MatchString(trimmed) && len(strings.ReplaceAll(trimmed, " ", "")) >= 3:
+			flushParagraph()
+			out.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			var quoted []string
+			for ; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(t, ">") {
+					i--
+					break
+				}
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(t, ">"), " "))
+			}
+			out.WriteString("<blockquote>\n")
+			out.WriteString(renderMarkdown(strings.Join(quoted, "\n")))
+			out.WriteString("</blockquote>\n")
+
+		case bulletItemPattern.MatchString(line) || orderedItemPattern.MatchString(line):
+			flushParagraph()
+			i = writeList(&out, lines, i)
+
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+
+	flushParagraph()
+	return out.String()
+}
+
+// fenceRun returns the run of backticks or tildes a code fence line starts
+// with, which may be longer than three to quote code containing fences
+func fenceRun(line string) string {
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	return line[:n]
+}
+
+// closesFence reports whether a trimmed line ends the code block opened by
+// fence: a run of the same character, at least as long, and nothing else
+func closesFence(line, fence string) bool {
+	return strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// writeList renders consecutive list items starting at lines[start] and
+// returns the index of the last line consumed
+func writeList(out *strings.Builder, lines []string, start int) int {
+	ordered := orderedItemPattern.MatchString(lines[start])
+	tag := "ul"
+	pattern := bulletItemPattern
+	if ordered {
+		tag = "ol"
+		pattern = orderedItemPattern
+	}
+
+	out.WriteString("<" + tag + ">\n")
+
+	i := start
+	for ; i < len(lines); i++ {
+		match := pattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			// Indented continuation lines belong to the previous item
+			if strings.HasPrefix(lines[i], "  ") && strings.TrimSpace(lines[i]) != "" {
+				out.WriteString("<br>" + renderInline(strings.TrimSpace(lines[i])))
+				continue
+			}
+			break
+		}
+		if i > start {
+			out.WriteString("</li>\n")
+		}
+		out.WriteString("<li>" + renderInline(match[1]))
+	}
+
+	out.WriteString("</li>\n</" + tag + ">\n")
+	return i - 1
+}
+
+// renderInline escapes text and applies inline code, emphasis and links.
+// Code spans are split out first so their contents are never reformatted.
+func renderInline(text string) string {
+	var out strings.Builder
+
+	segments := strings.Split(text, "`")
+	// An even number of segments means an unmatched backtick; treat it literally
+	if len(segments)%2 == 0 {
+		return renderEmphasis(escape(text))
+	}
+
+	for i, segment := range segments {
+		if i%2 == 1 {
+			out.WriteString("<code>" + escape(segment) + "</code>")
+			continue
+		}
+		out.WriteString(renderEmphasis(escape(segment)))
+	}
+
+	return out.String()
+}
+
+func renderEmphasis(escaped string) string {
+	result := boldPattern.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	result = italicPattern.ReplaceAllString(result, "$1$3<em>$2$4</em>")
+	result = linkPattern.ReplaceAllStringFunc(result, func(match string) string {
+		parts := linkPattern.FindStringSubmatch(match)
+		url := parts[2]
+		if !isSafeURL(url) {
+			return match
+		}
+		return "<a href=\"" + url + "\">" + parts[1] + "</a>"
+	})
+	return strings.ReplaceAll(result, "\n", "<br>\n")
+}
+
+func isSafeURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:") ||
+		strings.HasPrefix(lower, "#") ||
+		strings.HasPrefix(lower, "/")
+}
@@ -0,0 +1,82 @@
+package html
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownFences(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "backticks",
+			text:     "```go\nx := 1\n```\nafter",
+			contains: []string{`data-lang="go"`, "<p>after</p>"},
+		},
+		{
+			name:     "longer fence quotes a shorter one",
+			text:     "````md\n```go\n# not a heading\n```\n````\nafter",
+			contains: []string{`data-lang="md"`, "# not a heading", "<p>after</p>"},
+			excludes: []string{"<h3>"},
+		},
+		{
+			name:     "closing fence may be longer",
+			text:     "~~~\ncode\n~~~~~\nafter",
+			contains: []string{"<p>after</p>"},
+		},
+		{
+			name:     "other character does not close",
+			text:     "~~~\n```\n# still code\n~~~\n",
+			contains: []string{"# still code"},
+			excludes: []string{"<h3>"},
+		},
+		{
+			name:     "fence with info string does not close",
+			text:     "```\n```go\n# still code\n```\n",
+			contains: []string{"# still code"},
+			excludes: []string{"<h3>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderMarkdown(tt.text)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("output lacks %q:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("output contains %q:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderInlineLinks(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"[docs](https://opencode.ai)", `<a href="https://opencode.ai">docs</a>`},
+		{"[mail](mailto:a@b.c)", `<a href="mailto:a@b.c">mail</a>`},
+		{"[top](#top)", `<a href="#top">top</a>`},
+		{"[x](javascript:alert(1))", "[x](javascript:alert(1))"},
+		{"[x](JavaScript:alert)", "[x](JavaScript:alert)"},
+		{"[x](data:text/html,hi)", "[x](data:text/html,hi)"},
+		{"<script>", "&lt;script&gt;"},
+		{"`[x](https://a.b)`", "<code>[x](https://a.b)</code>"},
+	}
+
+	for _, tt := range tests {
+		if got := renderInline(tt.text); got != tt.want {
+			t.Errorf("renderInline(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package html
+
+// stylesheet is inlined into every export so the file renders without network access.
+// Colors are driven by custom properties; the dark palette applies when the OS prefers
+// it unless the reader has pinned a theme with the toggle button.
+const stylesheet = `:root {
+  --bg: #ffffff;
+  --fg: #1f2328;
+  --muted: #59636e;
+  --border: #d1d9e0;
+  --surface: #f6f8fa;
+  --user: #ddf4ff;
+  --assistant: #ffffff;
+  --accent: #0969da;
+  --error: #d1242f;
+  --tok-keyword: #cf222e;
+  --tok-string: #0a3069;
+  --tok-number: #0550ae;
+  --tok-literal: #8250df;
+  --tok-comment: #6e7781;
+  --tok-key: #116329;
+  --tok-inserted: #116329;
+  --tok-deleted: #82071e;
+}
+@media (prefers-color-scheme: dark) {
+  :root:not([data-theme="light"]) {
+    --bg: #0d1117;
+    --fg: #e6edf3;
+    --muted: #9198a1;
+    --border: #3d444d;
+    --surface: #151b23;
+    --user: #0c2d4b;
+    --assistant: #0d1117;
+    --accent: #4493f8;
+    --error: #f85149;
+    --tok-keyword: #ff7b72;
+    --tok-string: #a5d6ff;
+    --tok-number: #79c0ff;
+    --tok-literal: #d2a8ff;
+    --tok-comment: #8b949e;
+    --tok-key: #7ee787;
+    --tok-inserted: #7ee787;
+    --tok-deleted: #ffa198;
+  }
+}
+:root[data-theme="dark"] {
+  --bg: #0d1117;
+  --fg: #e6edf3;
+  --muted: #9198a1;
+  --border: #3d444d;
+  --surface: #151b23;
+  --user: #0c2d4b;
+  --assistant: #0d1117;
+  --accent: #4493f8;
+  --error: #f85149;
+  --tok-keyword: #ff7b72;
+  --tok-string: #a5d6ff;
+  --tok-number: #79c0ff;
+  --tok-literal: #d2a8ff;
+  --tok-comment: #8b949e;
+  --tok-key: #7ee787;
+  --tok-inserted: #7ee787;
+  --tok-deleted: #ffa198;
+}
+* { box-sizing: border-box; }
+body {
+  margin: 0;
+  background: var(--bg);
+  color: var(--fg);
+  font: 15px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+}
+main { max-width: 960px; margin: 0 auto; padding: 2rem 1rem 4rem; }
+a { color: var(--accent); }
+h1 { margin-top: 0; }
+code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 13px; }
+:not(pre) > code { background: var(--surface); border: 1px solid var(--border); border-radius: 4px; padding: 0 4px; }
+pre.code { background: var(--surface); border: 1px solid var(--border); border-radius: 6px; padding: 12px; overflow-x: auto; white-space: pre; }
+blockquote { margin: 0; padding-left: 1rem; border-left: 3px solid var(--border); color: var(--muted); }
+.session-header dl { display: grid; grid-template-columns: max-content 1fr; gap: 2px 12px; }
+.session-header dt { font-weight: 600; }
+.session-header dd { margin: 0; }
+.message { border: 1px solid var(--border); border-radius: 8px; padding: 0 1.25rem 1rem; margin: 1.5rem 0; background: var(--assistant); }
+.message.user { background: var(--user); }
+.message-header h2 { margin: 1rem 0 0; font-size: 1.15rem; }
+.meta { color: var(--muted); font-size: 13px; display: flex; flex-wrap: wrap; gap: 0 1rem; margin-bottom: 0.5rem; }
+details { border: 1px solid var(--border); border-radius: 6px; margin: 0.5rem 0; padding: 0 0.75rem; background: var(--bg); }
+details[open] { padding-bottom: 0.5rem; }
+summary { cursor: pointer; padding: 0.4rem 0; }
+.tool-title, .duration { color: var(--muted); }
+.status-error > summary strong { color: var(--error); }
+.error { color: var(--error); }
+.message-error { color: var(--error); border: 1px solid var(--error); border-radius: 6px; padding: 0.4rem 0.75rem; margin: 0.5rem 0; }
+.attachments { padding-left: 1.25rem; }
+.reasoning { margin: 0.5rem 0; }
+details.reasoning > summary, blockquote.reasoning strong { color: var(--muted); }
+.step-divider { display: flex; align-items: center; gap: 0.5rem; margin: 1rem 0 0.5rem; color: var(--muted); font-size: 12px; text-transform: uppercase; letter-spacing: 0.05em; }
+.step-divider::before, .step-divider::after { content: ""; flex: 1; border-top: 1px dashed var(--border); }
+.child-session { margin: 1rem 0; padding-left: 1rem; border-left: 3px solid var(--accent); }
+.child-session h1 { font-size: 1.3rem; }
+.theme-toggle { position: fixed; top: 1rem; right: 1rem; border: 1px solid var(--border); background: var(--surface); color: var(--fg); border-radius: 6px; padding: 4px 10px; cursor: pointer; font-size: 16px; }
+.tok-keyword { color: var(--tok-keyword); }
+.tok-string { color: var(--tok-string); }
+.tok-number { color: var(--tok-number); }
+.tok-literal { color: var(--tok-literal); }
+.tok-comment { color: var(--tok-comment); font-style: italic; }
+.tok-key { color: var(--tok-key); }
+.tok-inserted, .added { color: var(--tok-inserted); }
+.tok-deleted, .removed { color: var(--tok-deleted); }
+.tok-hunk { color: var(--accent); }
+.files-changed table, .session-totals table { border-collapse: collapse; }
+.files-changed th, .files-changed td, .session-totals th, .session-totals td { border: 1px solid var(--border); padding: 2px 10px; text-align: right; }
+.files-changed th:first-child, .files-changed td:first-child { text-align: left; }
+.usage, .snapshots { color: var(--muted); font-size: 13px; margin: 0.5rem 0; }
+.message-usage { font-weight: 600; }
+@media print {
+  .theme-toggle { display: none; }
+  details { border: none; }
+}
+`
+
+// themeScript restores the reader's last theme choice before first paint and
+// backs the toggle button. It is the only script in the document.
+const themeScript = `(function () {
+  try {
+    var saved = localStorage.getItem("ocse-theme");
+    if (saved) { document.documentElement.setAttribute("data-theme", saved); }
+  } catch (e) {}
+})();
+function toggleTheme() {
+  var root = document.documentElement;
+  var current = root.getAttribute("data-theme");
+  if (!current) {
+    current = window.matchMedia("(prefers-color-scheme: dark)").matches ? "dark" : "light";
+  }
+  var next = current === "dark" ? "light" : "dark";
+  root.setAttribute("data-theme", next);
+  try { localStorage.setItem("ocse-theme", next); } catch (e) {}
+}
+`
@@ -0,0 +1,390 @@
+// Package index maintains an on-disk inverted index of session text so that
+// repeated searches do not have to read and parse every storage file.
+//
+// The index stores the searchable fields of each session (see search.Fields)
+// and maps every lowercased word to the fields containing it. Each session is
+// stamped with the modification time and size of its storage files, and is
+// re-read only when one of those changes.
+package index
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fantomc0der/opencode-session-export/internal/search"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Version is incremented whenever the encoded layout or the tokenizer
+// changes. Index files of another version are discarded and rebuilt.
+const Version = 1
+
+// FileName is the name of the index file inside the cache directory
+const FileName = "search-index.gob"
+
+// Source is a session to index and the reader that can load it
+type Source struct {
+	SessionID   string
+	ProjectName string
+	Reader      *session.Reader
+}
+
+// Index is the persistent search index. Exported fields are what gets saved.
+type Index struct {
+	Version  int
+	Updated  time.Time
+	Sessions map[string]*Entry
+	// Terms maps each word to postings of the fields containing it, encoded
+	// as the entry's slot in the high 32 bits and the field index in the low
+	Terms    map[string][]uint64
+	NextSlot uint32
+
+	slots map[uint32]*Entry
+}
+
+// Entry is one indexed session
+type Entry struct {
+	Slot        uint32
+	SessionID   string
+	ProjectName string
+	Title       string
+	Created     int64
+	Updated     int64
+	InfoPath    string
+	Files       map[string]Stamp
+	Fields      []search.Field
+	Terms       []string // Distinct words of all fields, to remove postings on update
+}
+
+// Stamp identifies the version of a storage file
+type Stamp struct {
+	ModTime int64
+	Size    int64
+}
+
+// RefreshResult counts what a refresh changed
+type RefreshResult struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Removed   int
+	Failed    int
+}
+
+// Changed reports whether the refresh modified the index
+func (r RefreshResult) Changed() bool {
+	return r.Added+r.Updated+r.Removed > 0
+}
+
+// New returns an empty index
+func New() *Index {
+	return &Index{
+		Version:  Version,
+		Sessions: make(map[string]*Entry),
+		Terms:    make(map[string][]uint64),
+		slots:    make(map[uint32]*Entry),
+	}
+}
+
+// DefaultPath returns the index location inside cacheDir
+func DefaultPath(cacheDir string) string {
+	return filepath.Join(cacheDir, FileName)
+}
+
+// Open loads the index at path. A missing file, or one written by another
+// version, yields an empty index.
+func Open(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	defer file.Close()
+
+	var ix Index
+	if err := gob.NewDecoder(file).Decode(&ix); err != nil {
+		return nil, fmt.Errorf("failed to decode search index %s: %w", path, err)
+	}
+	if ix.Version != Version {
+		return New(), nil
+	}
+
+	if ix.Sessions == nil {
+		ix.Sessions = make(map[string]*Entry)
+	}
+	if ix.Terms == nil {
+		ix.Terms = make(map[string][]uint64)
+	}
+	ix.slots = make(map[uint32]*Entry, len(ix.Sessions))
+	for _, entry := range ix.Sessions {
+		ix.slots[entry.Slot] = entry
+	}
+
+	return &ix, nil
+}
+
+// Save writes the index to path, replacing it atomically so a concurrent
+// reader never sees a partial file
+func (ix *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), FileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(ix); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode search index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace search index: %w", err)
+	}
+
+	return nil
+}
+
+// Fields returns the number of indexed fields across all sessions
+func (ix *Index) Fields() int {
+	count := 0
+	for _, entry := range ix.Sessions {
+		count += len(entry.Fields)
+	}
+	return count
+}
+
+// Refresh brings the given sessions up to date, re-reading only those whose
+// storage files changed since they were indexed. When complete is set,
+// sources list every existing session and indexed sessions missing from them
+// are dropped. Otherwise sessions indexed earlier but not listed are kept, so
+// refreshing one project does not forget the others.
+//
+// Removals go by the listing rather than by storage files, since sessions
+// kept in a database all share its file.
+func (ix *Index) Refresh(sources []Source, complete bool) RefreshResult {
+	var result RefreshResult
+
+	for _, source := range sources {
+		infoPath, stamps, err := stampFiles(source)
+		if err != nil {
+			result.Failed++
+			continue
+		}
+
+		existing, ok := ix.Sessions[source.SessionID]
+		if ok && sameStamps(existing.Files, stamps) {
+			existing.ProjectName = source.ProjectName
+			result.Unchanged++
+			continue
+		}
+
+		sess, err := source.Reader.ReadSession(source.SessionID)
+		if err != nil {
+			result.Failed++
+			continue
+		}
+
+		if ok {
+			ix.remove(existing)
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		ix.add(source, sess, infoPath, stamps)
+	}
+
+	if complete {
+		for _, entry := range ix.missing(sources) {
+			ix.remove(entry)
+			result.Removed++
+		}
+	}
+
+	if result.Changed() {
+		ix.Updated = time.Now()
+	}
+
+	return result
+}
+
+// Check reports what Refresh would do without reading any sessions
+func (ix *Index) Check(sources []Source, complete bool) RefreshResult {
+	var result RefreshResult
+
+	for _, source := range sources {
+		_, stamps, err := stampFiles(source)
+		if err != nil {
+			result.Failed++
+			continue
+		}
+
+		existing, ok := ix.Sessions[source.SessionID]
+		switch {
+		case !ok:
+			result.Added++
+		case sameStamps(existing.Files, stamps):
+			result.Unchanged++
+		default:
+			result.Updated++
+		}
+	}
+
+	if complete {
+		result.Removed = len(ix.missing(sources))
+	}
+
+	return result
+}
+
+// missing returns the indexed sessions that are not in sources
+func (ix *Index) missing(sources []Source) []*Entry {
+	listed := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		listed[source.SessionID] = true
+	}
+
+	var entries []*Entry
+	for id, entry := range ix.Sessions {
+		if !listed[id] {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (ix *Index) add(source Source, sess *session.Session, infoPath string, stamps map[string]Stamp) {
+	entry := &Entry{
+		Slot:        ix.NextSlot,
+		SessionID:   source.SessionID,
+		ProjectName: source.ProjectName,
+		Title:       sess.Info.Title,
+		Created:     sess.Info.Time.Created,
+		Updated:     sess.Info.Time.Updated,
+		InfoPath:    infoPath,
+		Files:       stamps,
+		Fields:      search.Fields(sess),
+	}
+	ix.NextSlot++
+
+	seen := make(map[string]bool)
+	for i, field := range entry.Fields {
+		posting := uint64(entry.Slot)<<32 | uint64(i)
+		for _, term := range distinctTerms(field.Text) {
+			ix.Terms[term] = append(ix.Terms[term], posting)
+			if !seen[term] {
+				seen[term] = true
+				entry.Terms = append(entry.Terms, term)
+			}
+		}
+	}
+
+	ix.Sessions[entry.SessionID] = entry
+	ix.slots[entry.Slot] = entry
+}
+
+func (ix *Index) remove(entry *Entry) {
+	for _, term := range entry.Terms {
+		postings := ix.Terms[term][:0]
+		for _, posting := range ix.Terms[term] {
+			if uint32(posting>>32) != entry.Slot {
+				postings = append(postings, posting)
+			}
+		}
+		if len(postings) == 0 {
+			delete(ix.Terms, term)
+		} else {
+			ix.Terms[term] = postings
+		}
+	}
+
+	delete(ix.Sessions, entry.SessionID)
+	delete(ix.slots, entry.Slot)
+}
+
+// stampFiles records the modification time and size of every storage file
+// of a session
+func stampFiles(source Source) (string, map[string]Stamp, error) {
+	files, err := source.Reader.SessionFiles(source.SessionID)
+	if errors.Is(err, session.ErrNotFileStorage) {
+		return stampRecords(source)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	var infoPath string
+	stamps := make(map[string]Stamp, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return "", nil, err
+		}
+		stamps[file.Path] = Stamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+		if file.Kind == session.FileSessionInfo {
+			infoPath = file.Path
+		}
+	}
+
+	return infoPath, stamps, nil
+}
+
+// stampRecords stamps a session kept in a database with its update time, since
+// the database file changes with every session. opencode bumps the time
+// whenever a message of the session changes.
+func stampRecords(source Source) (string, map[string]Stamp, error) {
+	infoPath, err := source.Reader.InfoPath(source.SessionID)
+	if err != nil {
+		return "", nil, err
+	}
+	info, err := source.Reader.ReadSessionInfo(source.SessionID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := infoPath + "#" + source.SessionID
+	return infoPath, map[string]Stamp{key: {ModTime: info.Time.Updated}}, nil
+}
+
+func sameStamps(a, b map[string]Stamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, stamp := range a {
+		if b[path] != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+// distinctTerms splits text into lowercased runs of letters and digits
+func distinctTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool, len(words))
+	terms := words[:0]
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
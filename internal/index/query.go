@@ -0,0 +1,132 @@
+package index
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+
+	"github.com/fantomc0der/opencode-session-export/internal/search"
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Search runs a query over the indexed sessions listed in scope and returns
+// the matches in scope order. Sessions in scope that are not indexed are
+// skipped, so callers should Refresh first.
+//
+// Words the pattern cannot match without narrow the candidate fields through
+// the term postings; the pattern itself is then run on each candidate, so
+// results are identical to searching the sessions directly.
+func (ix *Index) Search(q *search.Query, scope []string) []search.Match {
+	candidates := ix.candidates(q.Pattern)
+
+	var matches []search.Match
+	for _, sessionID := range scope {
+		entry, ok := ix.Sessions[sessionID]
+		if !ok {
+			continue
+		}
+
+		info := session.SessionInfo{Time: session.TimeInfo{Created: entry.Created, Updated: entry.Updated}}
+		if !q.SessionInRange(&info) {
+			continue
+		}
+
+		var fieldIndexes []int
+		if candidates == nil {
+			for i := range entry.Fields {
+				fieldIndexes = append(fieldIndexes, i)
+			}
+		} else {
+			fieldIndexes = candidates[entry.Slot]
+			sort.Ints(fieldIndexes)
+		}
+
+		for _, i := range fieldIndexes {
+			match, ok := q.MatchField(entry.Fields[i])
+			if !ok {
+				continue
+			}
+			match.SessionID = entry.SessionID
+			match.SessionTitle = entry.Title
+			match.ProjectName = entry.ProjectName
+			matches = append(matches, match)
+		}
+	}
+
+	return matches
+}
+
+// candidates returns, per entry slot, the fields that contain every required
+// word of the pattern, or nil when the pattern has no required words and
+// every field must be checked
+func (ix *Index) candidates(pattern *regexp.Regexp) map[uint32][]int {
+	words := requiredWords(pattern)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var current map[uint64]bool
+	for _, word := range words {
+		// A required word may be only part of an indexed term, as "migration"
+		// is of "migrations", so every term containing it contributes
+		next := make(map[uint64]bool)
+		for term, postings := range ix.Terms {
+			if !strings.Contains(term, word) {
+				continue
+			}
+			for _, posting := range postings {
+				if current == nil || current[posting] {
+					next[posting] = true
+				}
+			}
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+
+	result := make(map[uint32][]int)
+	for posting := range current {
+		slot := uint32(posting >> 32)
+		result[slot] = append(result[slot], int(uint32(posting)))
+	}
+	return result
+}
+
+// requiredWords returns lowercased words that appear in every string the
+// pattern matches. Alternations and optional parts contribute nothing, so the
+// result is a safe filter rather than a complete description.
+func requiredWords(pattern *regexp.Regexp) []string {
+	re, err := syntax.Parse(pattern.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+
+	var literals []string
+	collectLiterals(re.Simplify(), &literals)
+
+	var words []string
+	for _, literal := range literals {
+		words = append(words, distinctTerms(literal)...)
+	}
+	return words
+}
+
+func collectLiterals(re *syntax.Regexp, literals *[]string) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		*literals = append(*literals, string(re.Rune))
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			collectLiterals(sub, literals)
+		}
+	case syntax.OpCapture, syntax.OpPlus:
+		collectLiterals(re.Sub[0], literals)
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			collectLiterals(re.Sub[0], literals)
+		}
+	}
+}
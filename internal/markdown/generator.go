@@ -1,56 +1,164 @@
 package markdown
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
+	"github.com/fantomc0der/opencode-session-export/internal/diff"
+	"github.com/fantomc0der/opencode-session-export/internal/render"
 	"github.com/fantomc0der/opencode-session-export/internal/session"
+	"github.com/fantomc0der/opencode-session-export/internal/snapshot"
 )
 
+func init() {
+	render.Register("markdown", func(opts render.Options) render.Renderer {
+		return NewGenerator(Options{
+			IncludeCosts:        opts.IncludeCosts,
+			IncludeTimings:      opts.IncludeTimings,
+			IncludeSnapshots:    opts.IncludeSnapshots,
+			SnapshotDiffs:       opts.SnapshotDiffs,
+			IncludeFilesChanged: opts.IncludeFilesChanged,
+			Reasoning:           opts.Reasoning,
+			Layout:              opts.Layout,
+			StepDividers:        opts.StepDividers,
+			Redactor:            opts.Redactor,
+		})
+	})
+}
+
 // Generator handles markdown generation from session data
 type Generator struct {
-	includeCosts     bool
-	includeTimings   bool
-	includeSnapshots bool
+	includeCosts        bool
+	includeTimings      bool
+	includeSnapshots    bool
+	snapshotDiffs       bool
+	includeFilesChanged bool
+	reasoning           render.ReasoningMode
+	layout              render.Layout
+	stepDividers        bool
+	redactor            render.TextRedactor
 }
 
 // Options configures the markdown generator
 type Options struct {
-	IncludeCosts     bool
-	IncludeTimings   bool
-	IncludeSnapshots bool
+	IncludeCosts        bool
+	IncludeTimings      bool
+	IncludeSnapshots    bool
+	SnapshotDiffs       bool                 // Diff patches against SessionInfo.SnapshotDir
+	IncludeFilesChanged bool                 // Summarize the files edited in the session
+	Reasoning           render.ReasoningMode // Default: render.ReasoningCollapse
+	Layout              render.Layout        // Default: render.LayoutInterleaved
+	StepDividers        bool                 // Mark where each step of a message starts
+	Redactor            render.TextRedactor  // Scrubs snapshot diffs, when set
 }
 
 // NewGenerator creates a new markdown generator
 func NewGenerator(opts Options) *Generator {
 	return &Generator{
-		includeCosts:     opts.IncludeCosts,
-		includeTimings:   opts.IncludeTimings,
-		includeSnapshots: opts.IncludeSnapshots,
+		includeCosts:        opts.IncludeCosts,
+		includeTimings:      opts.IncludeTimings,
+		includeSnapshots:    opts.IncludeSnapshots,
+		snapshotDiffs:       opts.SnapshotDiffs,
+		includeFilesChanged: opts.IncludeFilesChanged,
+		reasoning:           opts.Reasoning,
+		layout:              opts.Layout,
+		stepDividers:        opts.StepDividers,
+		redactor:            opts.Redactor,
 	}
 }
 
+// document is the state of rendering one session. It is kept apart from the
+// Generator, which may render several sessions at once.
+type document struct {
+	children  *render.Children
+	files     diff.Summary
+	totals    render.Totals
+	snapshots *snapshot.Repo // Resolves patch diffs, when enabled and available
+}
+
+// Render writes the markdown for a session to w. Child sessions are quoted
+// under the task calls that started them.
+func (g *Generator) Render(w io.Writer, sess *session.Session) error {
+	return g.stream(w, &sess.Info, sess.Walk, &document{children: render.NewChildren(sess.Children)})
+}
+
+// Extension returns the file extension for markdown exports
+func (g *Generator) Extension() string {
+	return ".md"
+}
+
+// MIMEType returns the media type of markdown exports
+func (g *Generator) MIMEType() string {
+	return "text/markdown; charset=utf-8"
+}
+
 // Generate creates markdown from a session
 func (g *Generator) Generate(sess *session.Session) (string, error) {
 	var md strings.Builder
+	if err := g.Render(&md, sess); err != nil {
+		return "", err
+	}
+	return md.String(), nil
+}
 
-	// Session header
-	g.writeSessionHeader(&md, &sess.Info)
+// Stream writes the markdown for a session to w as walk yields its
+// messages, so the document is never built in memory. Pass
+// session.Reader.WalkMessages to render a session straight from storage.
+func (g *Generator) Stream(w io.Writer, info *session.SessionInfo, walk func(session.WalkFunc) error) error {
+	return g.stream(w, info, walk, &document{})
+}
 
-	// Group parts by message
-	partsByMessage := g.groupPartsByMessage(sess.Parts)
+func (g *Generator) stream(w io.Writer, info *session.SessionInfo, walk func(session.WalkFunc) error, doc *document) error {
+	md := bufio.NewWriter(w)
+
+	if g.includeSnapshots && g.snapshotDiffs {
+		doc.snapshots = snapshot.Open(info.SnapshotDir)
+	}
+
+	// Session header
+	g.writeSessionHeader(md, info)
 
 	// Process messages in order
-	for i, message := range sess.Messages {
-		g.writeMessage(&md, &message, partsByMessage[message.ID], i+1)
+	messageNum := 0
+	err := walk(func(message *session.Message, parts []session.MessagePart) error {
+		messageNum++
+		if err := g.writeMessage(md, message, parts, messageNum, doc); err != nil {
+			return err
+		}
+
+		// Write errors are sticky, so this stops the walk on the first one
+		return md.Flush()
+	})
+	if err != nil {
+		return err
 	}
 
-	return md.String(), nil
+	if g.includeCosts {
+		g.writeTotals(md, &doc.totals)
+	}
+
+	if g.includeFilesChanged {
+		g.writeFilesChanged(md, &doc.files)
+	}
+
+	// Child sessions no task call in the transcript refers to
+	if remaining := doc.children.Remaining(); len(remaining) > 0 {
+		md.WriteString("## Child Sessions\n\n")
+		for _, child := range remaining {
+			if err := g.writeChildSession(md, child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return md.Flush()
 }
 
-func (g *Generator) writeSessionHeader(md *strings.Builder, info *session.SessionInfo) {
+func (g *Generator) writeSessionHeader(md *bufio.Writer, info *session.SessionInfo) {
 	md.WriteString(fmt.Sprintf("# Session: %s\n\n", info.Title))
 	md.WriteString(fmt.Sprintf("**Session ID:** `%s`  \n", info.ID))
 	md.WriteString(fmt.Sprintf("**Created:** %s  \n", info.GetCreatedAt().Format("2006-01-02 15:04:05")))
@@ -69,7 +177,7 @@ func (g *Generator) writeSessionHeader(md *strings.Builder, info *session.Sessio
 	md.WriteString("\n---\n\n")
 }
 
-func (g *Generator) writeMessage(md *strings.Builder, msg *session.Message, parts []session.MessagePart, messageNum int) {
+func (g *Generator) writeMessage(md *bufio.Writer, msg *session.Message, parts []session.MessagePart, messageNum int, doc *document) error {
 	// Message header
 	role := strings.Title(msg.Role)
 	md.WriteString(fmt.Sprintf("## Message %d: %s\n", messageNum, role))
@@ -78,78 +186,181 @@ func (g *Generator) writeMessage(md *strings.Builder, msg *session.Message, part
 	md.WriteString(fmt.Sprintf("**Timestamp:** %s", msg.GetCreatedAt().Format("15:04:05")))
 
 	// Assistant metadata
+	var usage session.Usage
 	if msg.Role == "assistant" {
+		usage = session.MessageUsage(msg, parts)
+		doc.totals.Add(msg, usage)
+
 		if msg.Model != nil {
 			md.WriteString(fmt.Sprintf(" | **Model:** %s", *msg.Model))
 		}
 		if g.includeCosts && msg.Cost != nil {
 			md.WriteString(fmt.Sprintf(" | **Cost:** $%.4f", *msg.Cost))
+		} else if g.includeCosts && usage.Steps > 0 {
+			md.WriteString(fmt.Sprintf(" | **Cost:** $%.4f", usage.Cost))
 		}
-		if msg.InputTokens != nil && msg.OutputTokens != nil {
-			md.WriteString(fmt.Sprintf(" | **Tokens:** %d in, %d out", *msg.InputTokens, *msg.OutputTokens))
+		if msg.Tokens != nil {
+			md.WriteString(fmt.Sprintf(" | **Tokens:** %s", render.FormatTokens(*msg.Tokens)))
 		}
 	}
 
 	md.WriteString("\n\n")
 
+	if msg.Error != nil {
+		md.WriteString(fmt.Sprintf("> ⚠️ **Error:** %s\n\n", msg.Error.Description()))
+	}
+
 	// Process parts
-	g.writeParts(md, parts)
+	if err := g.writeParts(md, msg, parts, doc); err != nil {
+		return err
+	}
+
+	// A single step's usage is the message's, and was written with the step
+	if g.includeCosts && usage.Steps > 1 {
+		md.WriteString(fmt.Sprintf("**Message total:** %d steps, %s · $%.4f\n\n",
+			usage.Steps, render.FormatTokens(usage.Tokens), usage.Cost))
+	}
 
 	md.WriteString("---\n\n")
+	return nil
 }
 
-func (g *Generator) writeParts(md *strings.Builder, parts []session.MessagePart) {
-	var textParts []session.MessagePart
-	var toolParts []session.MessagePart
-	var fileParts []session.MessagePart
-	var otherParts []session.MessagePart
-
-	// Group parts by type
-	for _, part := range parts {
-		switch part.Type {
-		case "text":
-			textParts = append(textParts, part)
-		case "tool":
-			toolParts = append(toolParts, part)
-		case "file":
-			fileParts = append(fileParts, part)
-		case "step-start", "step-finish":
-			// Skip step metadata parts - these are internal processing markers
-			continue
-		default:
-			otherParts = append(otherParts, part)
-		}
+func (g *Generator) writeParts(md *bufio.Writer, msg *session.Message, parts []session.MessagePart, doc *document) error {
+	layout := render.PartLayout{
+		Layout:       g.layout,
+		Reasoning:    g.reasoning,
+		StepDividers: g.stepDividers,
+		Snapshots:    g.includeSnapshots,
 	}
+	return layout.Write(&partWriter{g: g, md: md, doc: doc}, msg, parts)
+}
+
+// partWriter writes the parts of a message as markdown
+type partWriter struct {
+	g   *Generator
+	md  *bufio.Writer
+	doc *document
+}
+
+func (w *partWriter) Text(part *session.MessagePart) {
+	w.g.writeTextPart(w.md, *part)
+}
+
+func (w *partWriter) Reasoning(part *session.MessagePart, tokens int) {
+	w.g.writeReasoningPart(w.md, *part, tokens)
+}
+
+func (w *partWriter) Tool(part *session.MessagePart) error {
+	return w.g.writeToolCall(w.md, *part, w.doc)
+}
 
-	// Write text parts first
-	for _, part := range textParts {
-		g.writeTextPart(md, part)
+func (w *partWriter) StartAttachments(heading bool) {
+	if heading {
+		w.md.WriteString("### Attachments\n\n")
 	}
+}
 
-	// Write file attachments
-	if len(fileParts) > 0 {
-		md.WriteString("### Attachments\n\n")
-		for _, part := range fileParts {
-			g.writeFilePart(md, part)
-		}
-		md.WriteString("\n")
+func (w *partWriter) Attachment(part *session.MessagePart) {
+	w.g.writeFilePart(w.md, *part)
+}
+
+func (w *partWriter) EndAttachments() {
+	w.md.WriteString("\n")
+}
+
+func (w *partWriter) StartTools() {
+	w.md.WriteString("### Tool Executions\n\n")
+}
+
+func (w *partWriter) StepDivider(n int) {
+	w.md.WriteString(fmt.Sprintf("*— Step %d —*\n\n", n))
+}
+
+// StepFinish writes the snapshots a step recorded and its tokens and cost,
+// as far as the snapshot and cost options include them
+func (w *partWriter) StepFinish(step render.Step) {
+	if snapshots := step.Snapshots(code); w.g.includeSnapshots && snapshots != "" {
+		w.md.WriteString(fmt.Sprintf("📸 *Step %d snapshots:* %s\n\n", step.N, snapshots))
 	}
+	if w.g.includeCosts {
+		w.md.WriteString(fmt.Sprintf("*Step %d: %s · $%.4f*\n\n", step.N, render.FormatTokens(step.Finish.Tokens), step.Finish.Cost))
+	}
+}
 
-	// Write tool executions
-	if len(toolParts) > 0 {
-		md.WriteString("### Tool Executions\n\n")
-		for _, part := range toolParts {
-			g.writeToolPart(md, part)
+func (w *partWriter) Snapshot(hash string) {
+	w.md.WriteString(fmt.Sprintf("📸 **Snapshot:** `%s`\n\n", snapshot.Short(hash)))
+}
+
+// Patch lists the files a step changed, followed by their diff when the
+// session's snapshot repository is available to resolve it
+func (w *partWriter) Patch(patch *session.PatchPart, end string) {
+	w.md.WriteString(fmt.Sprintf("**Patch:** %d file(s) changed since snapshot `%s`\n\n", len(patch.Files), snapshot.Short(patch.Hash)))
+	for _, file := range patch.Files {
+		w.md.WriteString(fmt.Sprintf("- `%s`\n", file))
+	}
+	w.md.WriteString("\n")
+
+	changes, err := render.PatchDiff(w.doc.snapshots, patch, end, w.g.redactor)
+	if err != nil {
+		w.md.WriteString(fmt.Sprintf("*[Could not resolve snapshot diff: %v]*\n\n", err))
+		return
+	}
+	if changes != "" {
+		w.md.WriteString("```diff\n" + changes)
+		if !strings.HasSuffix(changes, "\n") {
+			w.md.WriteString("\n")
 		}
+		w.md.WriteString("```\n\n")
+	}
+}
+
+func (w *partWriter) Other(part *session.MessagePart) {
+	w.g.writeOtherPart(w.md, *part)
+}
+
+// code formats a snapshot hash as inline code
+func code(hash string) string {
+	return "`" + hash + "`"
+}
+
+// writeToolCall writes a tool part followed by the child session it started,
+// if the export includes it
+func (g *Generator) writeToolCall(md *bufio.Writer, part session.MessagePart, doc *document) error {
+	g.writeToolPart(md, part, doc)
+
+	if child := doc.children.Take(session.TaskSessionID(&part)); child != nil {
+		return g.writeChildSession(md, child)
 	}
+	return nil
+}
 
-	// Write other parts
-	for _, part := range otherParts {
-		g.writeOtherPart(md, part)
+// writeChildSession writes a child session's complete markdown as a block
+// quote, so its headings stay apart from the parent's
+func (g *Generator) writeChildSession(md *bufio.Writer, child *session.Session) error {
+	var nested strings.Builder
+	if err := g.Render(&nested, child); err != nil {
+		return err
 	}
+
+	md.WriteString(fmt.Sprintf("**Subagent session:** `%s`\n\n", child.Info.ID))
+	writeQuoted(md, nested.String())
+
+	return nil
 }
 
-func (g *Generator) writeTextPart(md *strings.Builder, part session.MessagePart) {
+// writeQuoted writes text as a block quote followed by a blank line
+func writeQuoted(md *bufio.Writer, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			md.WriteString(">\n")
+		} else {
+			md.WriteString("> " + line + "\n")
+		}
+	}
+	md.WriteString("\n")
+}
+
+func (g *Generator) writeTextPart(md *bufio.Writer, part session.MessagePart) {
 	// For text parts, the text is directly in the Text field
 	if part.Text != nil {
 		md.WriteString(*part.Text)
@@ -168,10 +379,40 @@ func (g *Generator) writeTextPart(md *strings.Builder, part session.MessagePart)
 	md.WriteString("\n\n")
 }
 
-func (g *Generator) writeToolPart(md *strings.Builder, part session.MessagePart) {
+// writeReasoningPart writes the model's reasoning folded in a <details>
+// block, or as a block quote when it is shown in full
+func (g *Generator) writeReasoningPart(md *bufio.Writer, part session.MessagePart, tokens int) {
+	text := strings.TrimSpace(session.ReasoningText(&part))
+	if text == "" {
+		return
+	}
+
+	var details []string
+	if tokens > 0 {
+		details = append(details, fmt.Sprintf("%d tokens", tokens))
+	}
+	if g.includeTimings && part.Time != nil && part.Time.End > part.Time.Start {
+		details = append(details, g.formatDuration(time.UnixMilli(part.Time.End).Sub(time.UnixMilli(part.Time.Start))))
+	}
+	label := "💭 Reasoning"
+	if len(details) > 0 {
+		label += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	if g.reasoning == render.ReasoningShow {
+		writeQuoted(md, fmt.Sprintf("**%s**\n\n%s", label, text))
+		return
+	}
+
+	md.WriteString(fmt.Sprintf("<details>\n<summary>%s</summary>\n\n", label))
+	md.WriteString(text)
+	md.WriteString("\n\n</details>\n\n")
+}
+
+func (g *Generator) writeToolPart(md *bufio.Writer, part session.MessagePart, doc *document) {
 	// For tool parts, the data is directly in the part fields
 	if part.Tool != nil && part.State != nil {
-		g.writeToolPartDirect(md, part)
+		g.writeToolPartDirect(md, part, doc)
 		return
 	}
 
@@ -182,10 +423,10 @@ func (g *Generator) writeToolPart(md *strings.Builder, part session.MessagePart)
 		return
 	}
 
-	g.writeToolPartFromData(md, toolData)
+	g.writeToolPartFromData(md, toolData, doc)
 }
 
-func (g *Generator) writeToolPartDirect(md *strings.Builder, part session.MessagePart) {
+func (g *Generator) writeToolPartDirect(md *bufio.Writer, part session.MessagePart, doc *document) {
 	var state session.ToolStateData
 	if err := json.Unmarshal(part.State, &state); err != nil {
 		md.WriteString(fmt.Sprintf("*[Error parsing tool state: %v]*\n\n", err))
@@ -215,8 +456,13 @@ func (g *Generator) writeToolPartDirect(md *strings.Builder, part session.Messag
 
 	md.WriteString("\n\n")
 
-	// Tool input
-	if state.Input != nil {
+	// Tool input, shown as a diff for file edits
+	if changes := diff.ToolChanges(toolName, state.Input, state.Metadata); len(changes) > 0 {
+		g.writeFileChanges(md, changes)
+		if state.Status == "completed" {
+			doc.files.Add(changes...)
+		}
+	} else if state.Input != nil {
 		md.WriteString("**Input:**\n")
 		g.writeCodeBlock(md, state.Input, toolName)
 		md.WriteString("\n")
@@ -244,7 +490,7 @@ func (g *Generator) writeToolPartDirect(md *strings.Builder, part session.Messag
 	}
 }
 
-func (g *Generator) writeToolPartFromData(md *strings.Builder, toolData session.ToolPartData) {
+func (g *Generator) writeToolPartFromData(md *bufio.Writer, toolData session.ToolPartData, doc *document) {
 	// Tool header with status
 	statusIcon := g.getStatusIcon(toolData.State.Status)
 	md.WriteString(fmt.Sprintf("#### %s %s", statusIcon, toolData.Tool))
@@ -267,8 +513,13 @@ func (g *Generator) writeToolPartFromData(md *strings.Builder, toolData session.
 
 	md.WriteString("\n\n")
 
-	// Tool input
-	if toolData.State.Input != nil {
+	// Tool input, shown as a diff for file edits
+	if changes := diff.ToolChanges(toolData.Tool, toolData.State.Input, toolData.State.Metadata); len(changes) > 0 {
+		g.writeFileChanges(md, changes)
+		if toolData.State.Status == "completed" {
+			doc.files.Add(changes...)
+		}
+	} else if toolData.State.Input != nil {
 		md.WriteString("**Input:**\n")
 		g.writeCodeBlock(md, toolData.State.Input, toolData.Tool)
 		md.WriteString("\n")
@@ -296,7 +547,71 @@ func (g *Generator) writeToolPartFromData(md *strings.Builder, toolData session.
 	}
 }
 
-func (g *Generator) writeFilePart(md *strings.Builder, part session.MessagePart) {
+// writeFileChanges writes the diff of each file a tool call changed
+func (g *Generator) writeFileChanges(md *bufio.Writer, changes []diff.FileChange) {
+	for _, change := range changes {
+		if change.Path != "" {
+			md.WriteString(fmt.Sprintf("**File:** `%s` (+%d -%d)\n", change.Path, change.Added, change.Removed))
+		}
+		md.WriteString("```diff\n")
+		md.WriteString(change.Diff)
+		md.WriteString("```\n\n")
+	}
+}
+
+// writeFilesChanged writes the lines added and removed per file over the
+// whole session
+func (g *Generator) writeFilesChanged(md *bufio.Writer, files *diff.Summary) {
+	if len(files.Files()) == 0 {
+		return
+	}
+
+	md.WriteString("## Files Changed\n\n")
+	md.WriteString("| File | Added | Removed |\n")
+	md.WriteString("|------|------:|--------:|\n")
+	for _, file := range files.Files() {
+		path := file.Path
+		if path == "" {
+			path = "(unknown)"
+		}
+		md.WriteString(fmt.Sprintf("| `%s` | +%d | -%d |\n", path, file.Added, file.Removed))
+	}
+	total := files.Total()
+	md.WriteString(fmt.Sprintf("| **Total: %d file(s)** | +%d | -%d |\n\n", len(files.Files()), total.Added, total.Removed))
+}
+
+// writeTotals writes the session's token and cost totals, and how they
+// compare with the costs messages report for themselves
+func (g *Generator) writeTotals(md *bufio.Writer, totals *render.Totals) {
+	if totals.Messages == 0 {
+		return
+	}
+
+	md.WriteString("## Session Totals\n\n")
+	writeTableRow(md, render.TotalsColumns)
+	md.WriteString("|")
+	for _, column := range render.TotalsColumns {
+		md.WriteString(strings.Repeat("-", len(column)+1) + ":|")
+	}
+	md.WriteString("\n")
+	writeTableRow(md, totals.Row())
+	md.WriteString("\n")
+
+	note, ok := totals.CostNote()
+	if note != "" && !ok {
+		note = "**Note:** " + note
+	}
+	if note != "" {
+		md.WriteString(note + "\n\n")
+	}
+}
+
+// writeTableRow writes cells as a row of a markdown table
+func writeTableRow(md *bufio.Writer, cells []string) {
+	md.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+}
+
+func (g *Generator) writeFilePart(md *bufio.Writer, part session.MessagePart) {
 	var fileData session.FilePartData
 	if err := json.Unmarshal(part.Data, &fileData); err != nil {
 		md.WriteString(fmt.Sprintf("*[Error parsing file part: %v]*\n", err))
@@ -314,7 +629,7 @@ func (g *Generator) writeFilePart(md *strings.Builder, part session.MessagePart)
 	md.WriteString("\n")
 }
 
-func (g *Generator) writeOtherPart(md *strings.Builder, part session.MessagePart) {
+func (g *Generator) writeOtherPart(md *bufio.Writer, part session.MessagePart) {
 	md.WriteString(fmt.Sprintf("### %s Part\n\n", strings.Title(part.Type)))
 	md.WriteString("```json\n")
 
@@ -333,7 +648,7 @@ func (g *Generator) writeOtherPart(md *strings.Builder, part session.MessagePart
 	md.WriteString("\n```\n\n")
 }
 
-func (g *Generator) writeCodeBlock(md *strings.Builder, data json.RawMessage, toolName string) {
+func (g *Generator) writeCodeBlock(md *bufio.Writer, data json.RawMessage, toolName string) {
 	// Try to determine language from tool name
 	lang := g.getLanguageFromTool(toolName)
 
@@ -364,14 +679,6 @@ func (g *Generator) writeCodeBlock(md *strings.Builder, data json.RawMessage, to
 	md.WriteString("\n```")
 }
 
-func (g *Generator) groupPartsByMessage(parts []session.MessagePart) map[string][]session.MessagePart {
-	result := make(map[string][]session.MessagePart)
-	for _, part := range parts {
-		result[part.MessageID] = append(result[part.MessageID], part)
-	}
-	return result
-}
-
 func (g *Generator) getStatusIcon(state string) string {
 	switch state {
 	case "completed":
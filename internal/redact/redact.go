@@ -0,0 +1,280 @@
+// Package redact removes secrets and personal data from sessions before they
+// are rendered. It runs between session.Reader and the export renderers.
+package redact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Redactor applies an ordered list of rules to session content
+type Redactor struct {
+	rules []Rule
+}
+
+// Report counts the substitutions made per rule
+type Report struct {
+	Counts map[string]int
+}
+
+// New creates a redactor that applies rules in order
+func New(rules []Rule) *Redactor {
+	return &Redactor{rules: rules}
+}
+
+// Fingerprint identifies the redactor's rules: their names, patterns and
+// replacements, in order. Redactors with the same rules share a fingerprint.
+func (rd *Redactor) Fingerprint() string {
+	hash := sha256.New()
+	for _, rule := range rd.rules {
+		fmt.Fprintf(hash, "%q %q %q\n", rule.Name, rule.Pattern.String(), rule.Replacement)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Total returns the number of substitutions across all rules
+func (r *Report) Total() int {
+	total := 0
+	for _, count := range r.Counts {
+		total += count
+	}
+	return total
+}
+
+// String formats the per-rule counts as "rule=count, ..." sorted by rule name
+func (r *Report) String() string {
+	names := make([]string, 0, len(r.Counts))
+	for name := range r.Counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, r.Counts[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *Report) add(rule string, count int) {
+	if count == 0 {
+		return
+	}
+	if r.Counts == nil {
+		r.Counts = make(map[string]int)
+	}
+	r.Counts[rule] += count
+}
+
+// RedactString applies every rule to s, recording substitutions in report
+func (rd *Redactor) RedactString(s string, report *Report) string {
+	for _, rule := range rd.rules {
+		var count int
+		s, count = replaceAll(rule, s)
+		report.add(rule.Name, count)
+	}
+	return s
+}
+
+// RedactText applies every rule to text without counting substitutions. It
+// lets renderers scrub text they add from outside the session.
+func (rd *Redactor) RedactText(text string) string {
+	var report Report
+	return rd.RedactString(text, &report)
+}
+
+// RedactSession scrubs a session and its child sessions in place and
+// reports what was replaced.
+//
+// Records read from storage carry their source file in Raw. Those are
+// redacted at the raw level and decoded again, so every field derived from
+// the file, and the raw data used by lossless exports, is scrubbed exactly
+// once and each secret is counted once.
+func (rd *Redactor) RedactSession(sess *session.Session) (Report, error) {
+	var report Report
+
+	if err := rd.RedactInfo(&sess.Info, &report); err != nil {
+		return report, err
+	}
+
+	for i := range sess.Messages {
+		if err := rd.RedactMessage(&sess.Messages[i], &report); err != nil {
+			return report, err
+		}
+	}
+
+	for i := range sess.Parts {
+		if err := rd.RedactPart(&sess.Parts[i], &report); err != nil {
+			return report, err
+		}
+	}
+
+	for _, child := range sess.Children {
+		childReport, err := rd.RedactSession(child)
+		for rule, count := range childReport.Counts {
+			report.add(rule, count)
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// RedactInfo scrubs session info in place, as RedactSession does. It and
+// RedactMessage and RedactPart let a session be redacted while it is
+// streamed rather than loaded whole.
+func (rd *Redactor) RedactInfo(info *session.SessionInfo, report *Report) error {
+	if info.Raw == nil {
+		info.Title = rd.RedactString(info.Title, report)
+		return nil
+	}
+
+	raw, err := rd.redactJSON(info.Raw, report)
+	if err != nil {
+		return fmt.Errorf("session info: %w", err)
+	}
+	var redacted session.SessionInfo
+	if err := json.Unmarshal(raw, &redacted); err != nil {
+		return fmt.Errorf("session info: %w", err)
+	}
+	redacted.Raw = raw
+	// Fields set by the reader rather than decoded from the file
+	redacted.SnapshotDir = info.SnapshotDir
+	*info = redacted
+
+	return nil
+}
+
+// RedactMessage scrubs a message in place, as RedactSession does
+func (rd *Redactor) RedactMessage(message *session.Message, report *Report) error {
+	if message.Raw == nil {
+		return nil
+	}
+
+	raw, err := rd.redactJSON(message.Raw, report)
+	if err != nil {
+		return fmt.Errorf("message %s: %w", message.ID, err)
+	}
+	var redacted session.Message
+	if err := json.Unmarshal(raw, &redacted); err != nil {
+		return fmt.Errorf("message %s: %w", message.ID, err)
+	}
+	redacted.Raw = raw
+	*message = redacted
+
+	return nil
+}
+
+// RedactPart scrubs a part in place, as RedactSession does
+func (rd *Redactor) RedactPart(part *session.MessagePart, report *Report) error {
+	if err := rd.redactPart(part, report); err != nil {
+		return fmt.Errorf("part %s: %w", part.ID, err)
+	}
+	return nil
+}
+
+func (rd *Redactor) redactPart(part *session.MessagePart, report *Report) error {
+	if part.Raw != nil {
+		raw, err := rd.redactJSON(part.Raw, report)
+		if err != nil {
+			return err
+		}
+		var redacted session.MessagePart
+		if err := json.Unmarshal(raw, &redacted); err != nil {
+			return err
+		}
+		redacted.Raw = raw
+		*part = redacted
+		return nil
+	}
+
+	if part.Text != nil {
+		text := rd.RedactString(*part.Text, report)
+		part.Text = &text
+	}
+
+	var err error
+	if part.State, err = rd.redactJSON(part.State, report); err != nil {
+		return err
+	}
+	if part.Data, err = rd.redactJSON(part.Data, report); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// redactJSON applies the rules to every string value in a JSON document.
+// Object keys are left alone since they are field names, not content.
+func (rd *Redactor) redactJSON(data json.RawMessage, report *Report) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as written instead of round-tripping them through float64
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	value = rd.redactValue(value, report)
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(bytes.TrimRight(buf.Bytes(), "\n")), nil
+}
+
+func (rd *Redactor) redactValue(value interface{}, report *Report) interface{} {
+	switch v := value.(type) {
+	case string:
+		return rd.RedactString(v, report)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = rd.redactValue(item, report)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rd.redactValue(item, report)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// replaceAll is regexp.ReplaceAllString that also reports the match count
+func replaceAll(rule Rule, s string) (string, int) {
+	matches := rule.Pattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, 0
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range matches {
+		out.WriteString(s[last:match[0]])
+		out.Write(rule.Pattern.ExpandString(nil, rule.Replacement, s, match))
+		last = match[1]
+	}
+	out.WriteString(s[last:])
+
+	return out.String(), len(matches)
+}
@@ -0,0 +1,185 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// Rule replaces every match of Pattern with Replacement. Replacement may
+// reference capture groups with ${1}, as in regexp.Regexp.Expand.
+type Rule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// marker returns the default replacement text for a rule
+func marker(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// BuiltinRules returns the detectors enabled by default. Rules that keep a
+// prefix such as "Bearer " capture it in group 1 so only the secret is replaced.
+// Order matters: env-secret runs before aws-secret-key so a .env line is
+// replaced, and counted, once.
+func BuiltinRules() []Rule {
+	return []Rule{
+		{
+			Name: "private-key",
+			// PEM private key blocks of any algorithm, including the armor lines
+			Pattern:     regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`),
+			Replacement: marker("private-key"),
+		},
+		{
+			Name: "aws-access-key",
+			// Long-term (AKIA) and temporary (ASIA) access key IDs
+			Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+			Replacement: marker("aws-access-key"),
+		},
+		{
+			Name: "github-token",
+			// Classic ghp_/gho_/ghu_/ghs_/ghr_ tokens and fine-grained github_pat_ tokens
+			Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+			Replacement: marker("github-token"),
+		},
+		{
+			Name: "jwt",
+			// Three base64url segments where header and payload are JSON objects ("eyJ" is "{"")
+			Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+			Replacement: marker("jwt"),
+		},
+		{
+			Name: "bearer-token",
+			// Authorization header values; 16 characters avoids prose like "bearer of"
+			Pattern:     regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]{16,}=*`),
+			Replacement: "${1}" + marker("bearer-token"),
+		},
+		{
+			Name: "env-secret",
+			// .env style assignments whose variable name suggests a credential
+			Pattern:     regexp.MustCompile(`(?m)^(\s*(?:export\s+)?[A-Za-z0-9_]*(?i:secret|token|password|passwd|api_?key|private_key|access_key|credentials?)[A-Za-z0-9_]*\s*=\s*)\S.*$`),
+			Replacement: "${1}" + marker("env-secret"),
+		},
+		{
+			Name: "aws-secret-key",
+			// The 40 character secret following an aws_secret_access_key assignment
+			Pattern:     regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[=:]\s*["']?)[A-Za-z0-9/+=]{40}`),
+			Replacement: "${1}" + marker("aws-secret-key"),
+		},
+		{
+			Name:        "email",
+			Pattern:     regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+			Replacement: marker("email"),
+		},
+		{
+			Name: "ipv4",
+			// Dotted quads with each octet limited to 0-255
+			Pattern:     regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+			Replacement: marker("ipv4"),
+		},
+		{
+			Name: "ipv6",
+			// Fully expanded addresses only; compressed forms collide with too much else
+			Pattern:     regexp.MustCompile(`\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b`),
+			Replacement: marker("ipv6"),
+		},
+		{
+			Name: "home-path",
+			// A user's home directory at the start of a path on Linux, macOS or
+			// Windows; group 1 keeps the character before it
+			Pattern:     regexp.MustCompile(`(^|[\s"'=:(\[])(?:/home/[^/\\\s"']+|/Users/[^/\\\s"']+|[A-Za-z]:\\Users\\[^/\\\s"']+|/root\b)`),
+			Replacement: "${1}~",
+		},
+	}
+}
+
+// Config is the on-disk redaction configuration
+type Config struct {
+	// DisableBuiltins lists built-in rule names to turn off
+	DisableBuiltins []string `json:"disableBuiltins,omitempty"`
+	// Rules are user-defined rules applied after the built-in ones
+	Rules []RuleConfig `json:"rules,omitempty"`
+}
+
+// RuleConfig is a user-defined rule. Pattern uses Go regexp syntax and
+// Replacement defaults to "[REDACTED:<name>]".
+type RuleConfig struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// LoadConfig reads a JSON redaction config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction config %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// BuildRules returns the built-in rules minus any disabled by cfg, followed
+// by cfg's own rules. A nil cfg yields just the built-in rules.
+func BuildRules(cfg *Config) ([]Rule, error) {
+	builtins := BuiltinRules()
+	if cfg == nil {
+		return builtins, nil
+	}
+
+	known := make(map[string]bool, len(builtins))
+	for _, rule := range builtins {
+		known[rule.Name] = true
+	}
+
+	disabled := make(map[string]bool, len(cfg.DisableBuiltins))
+	for _, name := range cfg.DisableBuiltins {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown built-in redaction rule %q (available: %v)", name, builtinNames(builtins))
+		}
+		disabled[name] = true
+	}
+
+	var rules []Rule
+	for _, rule := range builtins {
+		if !disabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	for i, rc := range cfg.Rules {
+		if rc.Name == "" {
+			return nil, fmt.Errorf("redaction rule %d has no name", i+1)
+		}
+		pattern, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %q has an invalid pattern: %w", rc.Name, err)
+		}
+
+		replacement := rc.Replacement
+		if replacement == "" {
+			replacement = marker(rc.Name)
+		}
+
+		rules = append(rules, Rule{Name: rc.Name, Pattern: pattern, Replacement: replacement})
+	}
+
+	return rules, nil
+}
+
+func builtinNames(rules []Rule) []string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	sort.Strings(names)
+	return names
+}
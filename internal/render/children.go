@@ -0,0 +1,48 @@
+package render
+
+import "github.com/fantomc0der/opencode-session-export/internal/session"
+
+// Children hands out the child sessions of a session being rendered, so a
+// format can nest each one under the task call that started it and write
+// the rest after the transcript. A nil *Children holds no sessions.
+type Children struct {
+	sessions []*session.Session
+	taken    map[string]bool
+}
+
+// NewChildren tracks sessions, or returns nil if there are none
+func NewChildren(sessions []*session.Session) *Children {
+	if len(sessions) == 0 {
+		return nil
+	}
+	return &Children{sessions: sessions, taken: make(map[string]bool)}
+}
+
+// Take returns the child session with the given ID unless it was already
+// taken, or nil
+func (c *Children) Take(sessionID string) *session.Session {
+	if c == nil || sessionID == "" || c.taken[sessionID] {
+		return nil
+	}
+	for _, child := range c.sessions {
+		if child.Info.ID == sessionID {
+			c.taken[sessionID] = true
+			return child
+		}
+	}
+	return nil
+}
+
+// Remaining returns the child sessions not taken yet, in order
+func (c *Children) Remaining() []*session.Session {
+	if c == nil {
+		return nil
+	}
+	var remaining []*session.Session
+	for _, child := range c.sessions {
+		if !c.taken[child.Info.ID] {
+			remaining = append(remaining, child)
+		}
+	}
+	return remaining
+}
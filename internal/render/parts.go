@@ -0,0 +1,272 @@
+package render
+
+import (
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+	"github.com/fantomc0der/opencode-session-export/internal/snapshot"
+)
+
+// PartWriter writes the parts of a message in one format. PartLayout.Write
+// calls its methods in the order the layout puts the parts in, so a format
+// only decides how each one looks.
+type PartWriter interface {
+	Text(part *session.MessagePart)
+	// Reasoning writes a reasoning part; tokens is its share of the
+	// message's reasoning tokens, or 0 if unknown
+	Reasoning(part *session.MessagePart, tokens int)
+	// Tool writes a tool call and anything the format nests under it
+	Tool(part *session.MessagePart) error
+	// StartAttachments begins a list of file attachments, under a heading in
+	// the grouped layout. Attachment writes each one, and EndAttachments
+	// ends the list.
+	StartAttachments(heading bool)
+	Attachment(part *session.MessagePart)
+	EndAttachments()
+	// StartTools writes the heading above the tool calls of the grouped
+	// layout
+	StartTools()
+	// StepDivider marks the start of step n, for steps after the first
+	StepDivider(n int)
+	// StepFinish writes what is known about a finished step
+	StepFinish(step Step)
+	// Snapshot writes a snapshot part recording the given snapshot
+	Snapshot(hash string)
+	// Patch writes a patch part; end is the snapshot its changes end at, or
+	// "" if unknown
+	Patch(patch *session.PatchPart, end string)
+	// Other writes a part no other method covers as its raw data
+	Other(part *session.MessagePart)
+}
+
+// PartLayout arranges the parts of a message for a PartWriter
+type PartLayout struct {
+	Layout       Layout
+	Reasoning    ReasoningMode // Reasoning parts are left out with ReasoningOmit
+	StepDividers bool          // Mark the start of each step after the first
+	Snapshots    bool          // Without it, patch and snapshot parts are written as other parts
+}
+
+// Step is a finished step of a message
+type Step struct {
+	N      int    // Position among the message's finished steps, from 1
+	Start  string // Snapshot the step started at, if recorded
+	Finish *session.StepFinish
+}
+
+// Snapshots names the snapshots the step started and finished at, each
+// abbreviated and formatted by code, or returns "" if it recorded neither
+func (s Step) Snapshots(code func(hash string) string) string {
+	switch {
+	case s.Start == "" && s.Finish.Snapshot == "":
+		return ""
+	case s.Start == "":
+		return "finished at " + code(snapshot.Short(s.Finish.Snapshot))
+	case s.Finish.Snapshot == "":
+		return "started at " + code(snapshot.Short(s.Start))
+	default:
+		return code(snapshot.Short(s.Start)) + " → " + code(snapshot.Short(s.Finish.Snapshot))
+	}
+}
+
+// PatchDiff returns the diff of the changes a patch recorded, read from repo
+// and scrubbed by redactor when it is set. It is empty if repo is nil or end
+// is unknown.
+func PatchDiff(repo *snapshot.Repo, patch *session.PatchPart, end string, redactor TextRedactor) (string, error) {
+	if repo == nil || end == "" {
+		return "", nil
+	}
+	changes, err := repo.Diff(patch.Hash, end)
+	if err != nil {
+		return "", err
+	}
+	if redactor != nil {
+		changes = redactor.RedactText(changes)
+	}
+	return changes, nil
+}
+
+// Write passes the parts of msg to w in the order of the layout. parts must
+// be in the order SortParts puts them in.
+func (l PartLayout) Write(w PartWriter, msg *session.Message, parts []session.MessagePart) error {
+	m := &messageParts{
+		PartLayout:      l,
+		w:               w,
+		reasoningTokens: session.ReasoningTokens(msg, parts),
+	}
+	if l.Snapshots {
+		m.patchEnds = session.PatchEnds(parts)
+	}
+
+	if l.Layout == LayoutGrouped {
+		return m.writeGrouped(parts)
+	}
+	return m.writeInterleaved(parts)
+}
+
+// messageParts is the state of writing the parts of one message
+type messageParts struct {
+	PartLayout
+	w               PartWriter
+	reasoningTokens map[string]int    // By reasoning part ID
+	patchEnds       map[string]string // Snapshot each patch ends at, by part ID
+
+	started  int    // Steps started so far
+	finished int    // Steps finished so far
+	start    string // Snapshot the current step started at
+}
+
+// writeInterleaved writes parts in the order they were created, so the text
+// between tool calls reads as it happened. Consecutive attachments form one
+// list.
+func (m *messageParts) writeInterleaved(parts []session.MessagePart) error {
+	inAttachments := false
+
+	for i := range parts {
+		part := &parts[i]
+
+		if inAttachments && part.Type != "file" {
+			m.w.EndAttachments()
+			inAttachments = false
+		}
+
+		switch part.Type {
+		case "tool":
+			if err := m.w.Tool(part); err != nil {
+				return err
+			}
+		case "file":
+			if !inAttachments {
+				m.w.StartAttachments(false)
+				inAttachments = true
+			}
+			m.w.Attachment(part)
+		case "step-start":
+			m.startStep(part)
+			if m.StepDividers && m.started > 1 {
+				m.w.StepDivider(m.started)
+			}
+		case "step-finish":
+			if step := m.finishStep(part); step != nil {
+				m.w.StepFinish(*step)
+			}
+		default:
+			m.writePart(part)
+		}
+	}
+
+	if inAttachments {
+		m.w.EndAttachments()
+	}
+
+	return nil
+}
+
+// writeGrouped writes a message's text and reasoning first, then its
+// attachments, then its tool calls, then any other parts, then its finished
+// steps and the changes they made
+func (m *messageParts) writeGrouped(parts []session.MessagePart) error {
+	var textParts []*session.MessagePart // Text and reasoning, in order
+	var toolParts []*session.MessagePart
+	var fileParts []*session.MessagePart
+	var otherParts []*session.MessagePart
+	var snapshotParts []*session.MessagePart // Patch and snapshot parts
+	var steps []Step
+
+	for i := range parts {
+		part := &parts[i]
+		switch part.Type {
+		case "text", "reasoning":
+			textParts = append(textParts, part)
+		case "tool":
+			toolParts = append(toolParts, part)
+		case "file":
+			fileParts = append(fileParts, part)
+		case "step-start":
+			m.startStep(part)
+		case "step-finish":
+			if step := m.finishStep(part); step != nil {
+				steps = append(steps, *step)
+			}
+		case "patch", "snapshot":
+			snapshotParts = append(snapshotParts, part)
+		default:
+			otherParts = append(otherParts, part)
+		}
+	}
+
+	for _, part := range textParts {
+		m.writePart(part)
+	}
+
+	if len(fileParts) > 0 {
+		m.w.StartAttachments(true)
+		for _, part := range fileParts {
+			m.w.Attachment(part)
+		}
+		m.w.EndAttachments()
+	}
+
+	if len(toolParts) > 0 {
+		m.w.StartTools()
+		for _, part := range toolParts {
+			if err := m.w.Tool(part); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, part := range otherParts {
+		m.w.Other(part)
+	}
+
+	for _, step := range steps {
+		m.w.StepFinish(step)
+	}
+	for _, part := range snapshotParts {
+		m.writePart(part)
+	}
+
+	return nil
+}
+
+// writePart writes a text, reasoning, patch, snapshot or other part
+func (m *messageParts) writePart(part *session.MessagePart) {
+	switch part.Type {
+	case "text":
+		m.w.Text(part)
+	case "reasoning":
+		if m.Reasoning != ReasoningOmit {
+			m.w.Reasoning(part, m.reasoningTokens[part.ID])
+		}
+	case "patch", "snapshot":
+		if !m.Snapshots {
+			m.w.Other(part)
+		} else if hash := session.ParseSnapshot(part); hash != "" {
+			m.w.Snapshot(hash)
+		} else if patch := session.ParsePatch(part); patch != nil {
+			m.w.Patch(patch, m.patchEnds[part.ID])
+		} else {
+			m.w.Other(part)
+		}
+	default:
+		m.w.Other(part)
+	}
+}
+
+func (m *messageParts) startStep(part *session.MessagePart) {
+	m.started++
+	m.start = ""
+	if start := session.ParseStepStart(part); start != nil {
+		m.start = start.Snapshot
+	}
+}
+
+// finishStep returns the step a step-finish part ends, or nil if the part
+// cannot be decoded
+func (m *messageParts) finishStep(part *session.MessagePart) *Step {
+	m.finished++
+	finish := session.ParseStepFinish(part)
+	if finish == nil {
+		return nil
+	}
+	return &Step{N: m.finished, Start: m.start, Finish: finish}
+}
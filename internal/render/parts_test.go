@@ -0,0 +1,217 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// recorder writes each call it receives as a line
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) add(format string, args ...interface{}) {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Text(part *session.MessagePart) {
+	r.add("text %s", part.ID)
+}
+
+func (r *recorder) Reasoning(part *session.MessagePart, tokens int) {
+	r.add("reasoning %s %d", part.ID, tokens)
+}
+
+func (r *recorder) Tool(part *session.MessagePart) error {
+	r.add("tool %s", part.ID)
+	return nil
+}
+
+func (r *recorder) StartAttachments(heading bool) {
+	r.add("attachments %v", heading)
+}
+
+func (r *recorder) Attachment(part *session.MessagePart) {
+	r.add("file %s", part.ID)
+}
+
+func (r *recorder) EndAttachments() {
+	r.add("end attachments")
+}
+
+func (r *recorder) StartTools() {
+	r.add("tools")
+}
+
+func (r *recorder) StepDivider(n int) {
+	r.add("divider %d", n)
+}
+
+func (r *recorder) Snapshot(hash string) {
+	r.add("snapshot %s", hash)
+}
+
+func (r *recorder) Other(part *session.MessagePart) {
+	r.add("other %s", part.ID)
+}
+
+func (r *recorder) StepFinish(step Step) {
+	r.add("step %d %s", step.N, step.Snapshots(func(hash string) string { return hash }))
+}
+
+func (r *recorder) Patch(patch *session.PatchPart, end string) {
+	r.add("patch %s..%s", patch.Hash, end)
+}
+
+// testParts decodes parts from JSON objects, in order
+func testParts(t *testing.T, objects ...string) []session.MessagePart {
+	t.Helper()
+	parts := make([]session.MessagePart, len(objects))
+	for i, object := range objects {
+		if err := json.Unmarshal([]byte(object), &parts[i]); err != nil {
+			t.Fatalf("%s: %v", object, err)
+		}
+		parts[i].Raw = json.RawMessage(object)
+	}
+	return parts
+}
+
+func TestPartLayoutWrite(t *testing.T) {
+	parts := testParts(t,
+		`{"id":"p01","type":"step-start","snapshot":"aaaa"}`,
+		`{"id":"p02","type":"reasoning","text":"think"}`,
+		`{"id":"p03","type":"file"}`,
+		`{"id":"p04","type":"file"}`,
+		`{"id":"p05","type":"text","text":"hi"}`,
+		`{"id":"p06","type":"tool","tool":"bash"}`,
+		`{"id":"p07","type":"step-finish","snapshot":"bbbb","tokens":{"input":1,"output":2,"reasoning":7}}`,
+		`{"id":"p08","type":"patch","hash":"aaaa","files":["a.txt"]}`,
+		`{"id":"p09","type":"step-start"}`,
+		`{"id":"p10","type":"agent"}`,
+		`{"id":"p11","type":"snapshot","snapshot":"cccc"}`,
+		`{"id":"p12","type":"file"}`,
+		`{"id":"p13","type":"step-finish","tokens":{"input":1,"output":2}}`,
+	)
+	msg := &session.Message{ID: "msg", Role: "assistant"}
+
+	tests := []struct {
+		name   string
+		layout PartLayout
+		want   []string
+	}{
+		{
+			name:   "interleaved",
+			layout: PartLayout{Layout: LayoutInterleaved, StepDividers: true, Snapshots: true},
+			want: []string{
+				"reasoning p02 7",
+				"attachments false", "file p03", "file p04", "end attachments",
+				"text p05",
+				"tool p06",
+				"step 1 aaaa → bbbb",
+				"patch aaaa..bbbb",
+				"divider 2",
+				"other p10",
+				"snapshot cccc",
+				"attachments false", "file p12", "end attachments",
+				"step 2 ",
+			},
+		},
+		{
+			name:   "grouped",
+			layout: PartLayout{Layout: LayoutGrouped, StepDividers: true, Snapshots: true},
+			want: []string{
+				"reasoning p02 7",
+				"text p05",
+				"attachments true", "file p03", "file p04", "file p12", "end attachments",
+				"tools", "tool p06",
+				"other p10",
+				"step 1 aaaa → bbbb",
+				"step 2 ",
+				"patch aaaa..bbbb",
+				"snapshot cccc",
+			},
+		},
+		{
+			name:   "without reasoning, dividers or snapshots",
+			layout: PartLayout{Layout: LayoutInterleaved, Reasoning: ReasoningOmit},
+			want: []string{
+				"attachments false", "file p03", "file p04", "end attachments",
+				"text p05",
+				"tool p06",
+				"step 1 aaaa → bbbb",
+				"other p08",
+				"other p10",
+				"other p11",
+				"attachments false", "file p12", "end attachments",
+				"step 2 ",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			if err := tt.layout.Write(&r, msg, parts); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if !reflect.DeepEqual(r.calls, tt.want) {
+				t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(r.calls, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestStepSnapshots(t *testing.T) {
+	tests := []struct {
+		start, finish string
+		want          string
+	}{
+		{"", "", ""},
+		{"0123456789ab", "", "started at <01234567>"},
+		{"", "0123456789ab", "finished at <01234567>"},
+		{"aaaa", "bbbb", "<aaaa> → <bbbb>"},
+	}
+
+	for _, tt := range tests {
+		step := Step{Start: tt.start, Finish: &session.StepFinish{Snapshot: tt.finish}}
+		got := step.Snapshots(func(hash string) string { return "<" + hash + ">" })
+		if got != tt.want {
+			t.Errorf("Snapshots() for %q → %q = %q, want %q", tt.start, tt.finish, got, tt.want)
+		}
+	}
+}
+
+func TestTotalsCostNote(t *testing.T) {
+	cost := func(c float64) *float64 { return &c }
+
+	tests := []struct {
+		name     string
+		messages []*session.Message
+		usage    []float64 // Step cost of each message
+		want     string
+		ok       bool
+	}{
+		{"no reported costs", []*session.Message{{}}, []float64{0.5}, "", true},
+		{"matching", []*session.Message{{Cost: cost(0.1)}, {Cost: cost(0.2)}}, []float64{0.1, 0.2}, "Message costs total $0.3000, matching the steps.", true},
+		{"rounding", []*session.Message{{Cost: cost(0.10001)}}, []float64{0.1}, "Message costs total $0.1000, matching the steps.", true},
+		{"differing", []*session.Message{{Cost: cost(0.5)}}, []float64{0.1}, "Message costs total $0.5000, but the steps account for $0.1000.", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var totals Totals
+			for i, message := range tt.messages {
+				totals.Add(message, session.Usage{Steps: 1, Cost: tt.usage[i]})
+			}
+			note, ok := totals.CostNote()
+			if note != tt.want || ok != tt.ok {
+				t.Errorf("CostNote() = %q, %v; want %q, %v", note, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+// Package render defines the pluggable export format interface and the
+// registry that maps format names such as "markdown" or "html" to renderers.
+//
+// Format packages register themselves from an init function, in the same way
+// database/sql drivers do, so a new format only needs to be imported by the
+// CLI to become available through --format.
+//
+// The package also holds what the formats share, such as the order a
+// message's parts are written in and the usage totals, so each format only
+// decides how things look.
+package render
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Renderer writes a session in a single export format
+type Renderer interface {
+	// Render writes the complete document for sess to w
+	Render(w io.Writer, sess *session.Session) error
+	// Extension returns the file extension for exported files, including the dot
+	Extension() string
+	// MIMEType returns the media type of the rendered document
+	MIMEType() string
+}
+
+// Streamer is implemented by renderers that can write a session while it is
+// read, one message at a time, so large sessions never have to fit in memory
+type Streamer interface {
+	// Stream writes the complete document for the session described by info,
+	// whose messages and parts walk yields in order
+	Stream(w io.Writer, info *session.SessionInfo, walk func(session.WalkFunc) error) error
+}
+
+// Options holds the rendering options shared by all formats.
+// Formats ignore options that do not apply to them.
+type Options struct {
+	IncludeCosts        bool          `json:"includeCosts,omitempty"`
+	IncludeTimings      bool          `json:"includeTimings,omitempty"`
+	IncludeSnapshots    bool          `json:"includeSnapshots,omitempty"`
+	SnapshotDiffs       bool          `json:"snapshotDiffs,omitempty"` // Diff patches against the snapshot repository
+	IncludeFilesChanged bool          `json:"includeFilesChanged,omitempty"`
+	Reasoning           ReasoningMode `json:"reasoning,omitempty"`
+	Layout              Layout        `json:"layout,omitempty"`
+	StepDividers        bool          `json:"stepDividers,omitempty"`
+
+	// Redactor, when set, scrubs what formats add to an export from outside
+	// the session, such as snapshot diffs, which session redaction never sees
+	Redactor TextRedactor `json:"-"`
+}
+
+// TextRedactor scrubs secrets and personal data from text
+type TextRedactor interface {
+	RedactText(text string) string
+}
+
+// Layout is how formats arrange the parts of a message
+type Layout string
+
+const (
+	// LayoutInterleaved writes parts in the order they were created, so text
+	// and tool calls read as they happened; it is the default
+	LayoutInterleaved Layout = "interleaved"
+	// LayoutGrouped writes a message's text first, then its attachments,
+	// then its tool calls
+	LayoutGrouped Layout = "grouped"
+)
+
+// ParseLayout validates a --layout value. The empty string selects the
+// default, LayoutInterleaved.
+func ParseLayout(value string) (Layout, error) {
+	switch layout := Layout(strings.ToLower(value)); layout {
+	case "":
+		return LayoutInterleaved, nil
+	case LayoutInterleaved, LayoutGrouped:
+		return layout, nil
+	default:
+		return "", fmt.Errorf("invalid layout %q (use interleaved or grouped)", value)
+	}
+}
+
+// ReasoningMode is how formats show the model's reasoning
+type ReasoningMode string
+
+const (
+	// ReasoningCollapse shows reasoning folded away, where the format can
+	// fold content; it is the default
+	ReasoningCollapse ReasoningMode = "collapse"
+	// ReasoningShow shows reasoning in full, set apart from the reply
+	ReasoningShow ReasoningMode = "show"
+	// ReasoningOmit leaves reasoning out
+	ReasoningOmit ReasoningMode = "omit"
+)
+
+// ParseReasoningMode validates a --reasoning value. The empty string selects
+// the default, ReasoningCollapse.
+func ParseReasoningMode(value string) (ReasoningMode, error) {
+	switch mode := ReasoningMode(strings.ToLower(value)); mode {
+	case "":
+		return ReasoningCollapse, nil
+	case ReasoningShow, ReasoningCollapse, ReasoningOmit:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid reasoning mode %q (use show, collapse or omit)", value)
+	}
+}
+
+// Factory creates a renderer configured with the given options
+type Factory func(opts Options) Renderer
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a format available under name. It panics if the name is
+// empty, the factory is nil or the name is already registered, since all
+// of those are programming errors caught at startup.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name = strings.ToLower(name)
+	if name == "" {
+		panic("render: Register called with empty format name")
+	}
+	if factory == nil {
+		panic("render: Register factory is nil for format " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic("render: Register called twice for format " + name)
+	}
+
+	registry[name] = factory
+}
+
+// New creates a renderer for the named format
+func New(name string, opts Options) (Renderer, error) {
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(name)]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+
+	return factory(opts), nil
+}
+
+// Names returns the sorted names of all registered formats
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
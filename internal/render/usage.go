@@ -0,0 +1,85 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// costTolerance is how far step costs may drift from a message's reported
+// cost through rounding and still count as matching
+const costTolerance = 0.00005
+
+// Totals accumulates the usage of a session's assistant messages, with the
+// costs the messages report for themselves to check the steps against
+type Totals struct {
+	Messages     int
+	Usage        session.Usage
+	ReportedCost float64 // Sum of the messages' own Cost
+	Reported     int     // Messages that report a cost
+}
+
+// Add counts an assistant message and the usage of its steps
+func (t *Totals) Add(message *session.Message, usage session.Usage) {
+	t.Messages++
+	t.Usage.Add(usage)
+	if message.Cost != nil {
+		t.ReportedCost += *message.Cost
+		t.Reported++
+	}
+}
+
+// Reconciles reports whether the step costs add up to the reported message
+// costs. It is true when no message reports a cost.
+func (t *Totals) Reconciles() bool {
+	return t.Reported == 0 || math.Abs(t.Usage.Cost-t.ReportedCost) < costTolerance
+}
+
+// TotalsColumns heads the columns of Totals.Row
+var TotalsColumns = []string{"Messages", "Steps", "Input", "Output", "Reasoning", "Cache Read", "Cache Write", "Cost"}
+
+// Row returns the totals as the cells of a table headed by TotalsColumns
+func (t *Totals) Row() []string {
+	usage := t.Usage
+	return []string{
+		fmt.Sprint(t.Messages),
+		fmt.Sprint(usage.Steps),
+		fmt.Sprint(usage.Tokens.Input),
+		fmt.Sprint(usage.Tokens.Output),
+		fmt.Sprint(usage.Tokens.Reasoning),
+		fmt.Sprint(usage.Tokens.Cache.Read),
+		fmt.Sprint(usage.Tokens.Cache.Write),
+		fmt.Sprintf("$%.4f", usage.Cost),
+	}
+}
+
+// CostNote compares the costs messages report for themselves with the steps'
+// total, reporting whether they match. It returns "" when no message reports
+// a cost.
+func (t *Totals) CostNote() (string, bool) {
+	if t.Reported == 0 {
+		return "", true
+	}
+	if t.Reconciles() {
+		return fmt.Sprintf("Message costs total $%.4f, matching the steps.", t.ReportedCost), true
+	}
+	return fmt.Sprintf("Message costs total $%.4f, but the steps account for $%.4f.", t.ReportedCost, t.Usage.Cost), false
+}
+
+// FormatTokens describes token counts in a line, leaving out the zero
+// reasoning and cache counts, e.g. "1200 in, 340 out, 1000 cache read"
+func FormatTokens(tokens session.StepTokens) string {
+	fields := []string{fmt.Sprintf("%d in", tokens.Input), fmt.Sprintf("%d out", tokens.Output)}
+	if tokens.Reasoning > 0 {
+		fields = append(fields, fmt.Sprintf("%d reasoning", tokens.Reasoning))
+	}
+	if tokens.Cache.Read > 0 {
+		fields = append(fields, fmt.Sprintf("%d cache read", tokens.Cache.Read))
+	}
+	if tokens.Cache.Write > 0 {
+		fields = append(fields, fmt.Sprintf("%d cache write", tokens.Cache.Write))
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,173 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Field kinds
+const (
+	KindTitle     = "title"
+	KindText      = "text"
+	KindReasoning = "reasoning"
+	KindInput     = "input"
+	KindOutput    = "output"
+)
+
+// Field is one searchable piece of text from a session
+type Field struct {
+	MessageID  string
+	MessageNum int // 1-based position in the transcript, 0 for the session title
+	Role       string
+	Tool       string // Tool name for input and output fields
+	Kind       string
+	Time       time.Time
+	Text       string
+}
+
+// Fields extracts the searchable text of a session: its title, text and
+// reasoning parts, and tool inputs and outputs. Message numbers match the
+// "Message N" headings of the markdown export.
+func Fields(sess *session.Session) []Field {
+	fields := []Field{{
+		Kind: KindTitle,
+		Time: sess.Info.GetUpdatedAt(),
+		Text: sess.Info.Title,
+	}}
+
+	messages := make(map[string]int, len(sess.Messages))
+	for i, message := range sess.Messages {
+		messages[message.ID] = i
+	}
+
+	for _, part := range sess.Parts {
+		index, ok := messages[part.MessageID]
+		if !ok {
+			continue // Parts of unreadable messages are not in the transcript either
+		}
+		message := sess.Messages[index]
+
+		base := Field{
+			MessageID:  message.ID,
+			MessageNum: index + 1,
+			Role:       message.Role,
+			Time:       message.GetCreatedAt(),
+		}
+		if base.Time.IsZero() {
+			base.Time = part.GetCreatedAt()
+		}
+
+		fields = append(fields, partFields(base, part)...)
+	}
+
+	return fields
+}
+
+func partFields(base Field, part session.MessagePart) []Field {
+	switch part.Type {
+	case "text", "reasoning":
+		text := ""
+		if part.Text != nil {
+			text = *part.Text
+		} else {
+			var textData session.TextPartData
+			if err := json.Unmarshal(part.Data, &textData); err != nil {
+				return nil
+			}
+			text = textData.Text
+		}
+
+		base.Kind = KindText
+		if part.Type == "reasoning" {
+			base.Kind = KindReasoning
+		}
+		base.Text = text
+		return []Field{base}
+
+	case "tool":
+		var tool string
+		var state session.ToolStateData
+		if part.Tool != nil && part.State != nil {
+			if err := json.Unmarshal(part.State, &state); err != nil {
+				return nil
+			}
+			tool = *part.Tool
+		} else {
+			var toolData session.ToolPartData
+			if err := json.Unmarshal(part.Data, &toolData); err != nil {
+				return nil
+			}
+			tool = toolData.Tool
+			state = toolData.State
+		}
+
+		base.Tool = tool
+		var fields []Field
+		if input := flattenJSON(state.Input); input != "" {
+			field := base
+			field.Kind = KindInput
+			field.Text = input
+			fields = append(fields, field)
+		}
+		if output := flattenValue(state.Output); output != "" {
+			field := base
+			field.Kind = KindOutput
+			field.Text = output
+			fields = append(fields, field)
+		}
+		return fields
+	}
+
+	return nil
+}
+
+// flattenJSON returns the string values of a JSON document one per line, so
+// queries match what the tool actually received rather than its JSON escaping
+func flattenJSON(data json.RawMessage) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	return flattenValue(value)
+}
+
+func flattenValue(value interface{}) string {
+	var lines []string
+	collectStrings(value, &lines)
+	return strings.Join(lines, "\n")
+}
+
+func collectStrings(value interface{}, lines *[]string) {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		if v != "" {
+			*lines = append(*lines, v)
+		}
+	case map[string]interface{}:
+		// Sort keys so fields, and snippets, come out the same on every run
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectStrings(v[key], lines)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectStrings(item, lines)
+		}
+	default:
+		*lines = append(*lines, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,200 @@
+// Package search finds text across sessions. It matches a query against the
+// title, text and reasoning parts, and tool inputs and outputs of a session
+// and reports each hit with a snippet of the surrounding text.
+package search
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// snippetContext is how many bytes of text to keep on each side of a match
+const snippetContext = 60
+
+// Query describes what to search for
+type Query struct {
+	Pattern *regexp.Regexp
+	Roles   []string  // Only match messages with these roles; empty matches all
+	Tools   []string  // Only match input and output of these tools; empty matches all
+	Since   time.Time // Only match content created at or after this time
+	Until   time.Time // Only match content created before this time
+}
+
+// Match is a single hit, one per matching field
+type Match struct {
+	SessionID    string
+	SessionTitle string
+	ProjectName  string
+	Field        Field
+	Count        int // Number of matches within the field
+	Snippet      string
+	Highlights   [][2]int // Byte ranges of matches within Snippet
+}
+
+// NewQuery compiles pattern into a query. Unless regex is set the pattern is
+// matched literally; matching ignores case unless caseSensitive is set.
+func NewQuery(pattern string, regex, caseSensitive bool) (*Query, error) {
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{Pattern: re}, nil
+}
+
+// SessionInRange reports whether a session was active at any point in the
+// query's date range, so sessions outside it can be skipped without loading
+func (q *Query) SessionInRange(info *session.SessionInfo) bool {
+	if !q.Since.IsZero() && info.Time.Updated != 0 && info.GetUpdatedAt().Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && info.Time.Created != 0 && !info.GetCreatedAt().Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Accepts reports whether a field passes the query's role, tool and date
+// filters. The title only matches when no role or tool filter is set.
+func (q *Query) Accepts(field Field) bool {
+	if field.Kind == KindTitle {
+		return len(q.Roles) == 0 && len(q.Tools) == 0
+	}
+
+	if len(q.Roles) > 0 && !containsFold(q.Roles, field.Role) {
+		return false
+	}
+	if len(q.Tools) > 0 && (field.Tool == "" || !containsFold(q.Tools, field.Tool)) {
+		return false
+	}
+
+	if !field.Time.IsZero() {
+		if !q.Since.IsZero() && field.Time.Before(q.Since) {
+			return false
+		}
+		if !q.Until.IsZero() && !field.Time.Before(q.Until) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchField returns the hit for a single field, if the query matches it
+func (q *Query) MatchField(field Field) (Match, bool) {
+	if !q.Accepts(field) {
+		return Match{}, false
+	}
+
+	locations := q.Pattern.FindAllStringIndex(field.Text, -1)
+	if len(locations) == 0 {
+		return Match{}, false
+	}
+
+	snippet, highlights := makeSnippet(field.Text, locations)
+	return Match{
+		Field:      field,
+		Count:      len(locations),
+		Snippet:    snippet,
+		Highlights: highlights,
+	}, true
+}
+
+// Session searches one loaded session
+func (q *Query) Session(sess *session.Session, projectName string) []Match {
+	var matches []Match
+	for _, field := range Fields(sess) {
+		match, ok := q.MatchField(field)
+		if !ok {
+			continue
+		}
+		match.SessionID = sess.Info.ID
+		match.SessionTitle = sess.Info.Title
+		match.ProjectName = projectName
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// makeSnippet cuts a window of text around the first match and translates
+// every match inside the window into snippet offsets. Line breaks, tabs and
+// other ASCII control characters are replaced by spaces byte-for-byte so the
+// offsets stay valid and the snippet is safe to print on a terminal.
+func makeSnippet(text string, locations [][]int) (string, [][2]int) {
+	start := max(locations[0][0]-snippetContext, 0)
+	end := min(locations[0][1]+snippetContext, len(text))
+
+	// Move inward to rune boundaries so the window never splits a character
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(text) {
+		suffix = "…"
+	}
+
+	window := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, text[start:end])
+
+	var highlights [][2]int
+	for _, location := range locations {
+		if location[0] < start || location[1] > end {
+			continue
+		}
+		if location[0] == location[1] {
+			continue // Empty matches have nothing to highlight
+		}
+		highlights = append(highlights, [2]int{
+			location[0] - start + len(prefix),
+			location[1] - start + len(prefix),
+		})
+	}
+
+	return prefix + window + suffix, highlights
+}
+
+// Highlight wraps each highlighted range of the snippet in opener and closer
+func (m *Match) Highlight(opener, closer string) string {
+	var out strings.Builder
+	last := 0
+	for _, h := range m.Highlights {
+		out.WriteString(m.Snippet[last:h[0]])
+		out.WriteString(opener)
+		out.WriteString(m.Snippet[h[0]:h[1]])
+		out.WriteString(closer)
+		last = h[1]
+	}
+	out.WriteString(m.Snippet[last:])
+	return out.String()
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
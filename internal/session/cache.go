@@ -0,0 +1,292 @@
+package session
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/config"
+)
+
+// SessionCacheFile is the name of the session cache inside the cache directory
+const SessionCacheFile = "sessions.gob"
+
+// sessionCacheVersion is incremented whenever the cached layout changes, or
+// caches written by an earlier version hold wrong values. Caches of another
+// version are discarded. Version 1 caches may lack session directories.
+const sessionCacheVersion = 2
+
+// racyWindow is how long after a directory's modification time a listing of
+// it is still distrusted, since a file added in the same clock tick would not
+// change the time again
+const racyWindow = time.Second
+
+// sessionCache remembers the session info files found in each storage
+// directory, so listing sessions does not have to open every one of them.
+//
+// A directory's listing is reused while its modification time is unchanged,
+// which holds until a session is created or deleted in it. A session's
+// metadata is reused while its info file's modification time and size are.
+// Exported fields are what gets saved.
+type sessionCache struct {
+	Version int
+	Dirs    map[string]*cachedDir
+
+	mu    sync.Mutex
+	path  string // Where the cache is saved; empty if it cannot be
+	dirty bool
+}
+
+// cachedDir is the listing of a directory of session info files
+type cachedDir struct {
+	ModTime  int64
+	Scanned  int64
+	Sessions map[string]*cachedSession // By session ID
+}
+
+// cachedSession is the decoded info file of one session
+type cachedSession struct {
+	ID        string // From the file name, which is what lookups use
+	Path      string
+	Directory string // Project directory, recorded in the hash layout only
+	Info      SessionInfo
+	ModTime   int64
+	Size      int64
+}
+
+var (
+	cacheOnce   sync.Once
+	sharedCache *sessionCache
+)
+
+// loadCache returns the session cache shared by all readers, loading it from
+// the cache directory on first use. The cache only saves work, so a missing
+// or unreadable file yields an empty one.
+func loadCache() *sessionCache {
+	cacheOnce.Do(func() {
+		sharedCache = &sessionCache{Dirs: make(map[string]*cachedDir)}
+
+		cacheDir, err := config.GetCacheDir()
+		if err != nil {
+			return
+		}
+		sharedCache.path = filepath.Join(cacheDir, SessionCacheFile)
+
+		file, err := os.Open(sharedCache.path)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+
+		var saved sessionCache
+		if err := gob.NewDecoder(file).Decode(&saved); err != nil || saved.Version != sessionCacheVersion || saved.Dirs == nil {
+			return
+		}
+		sharedCache.Dirs = saved.Dirs
+	})
+	return sharedCache
+}
+
+// sessions returns the sessions whose info files are in dir, ordered by ID.
+// With checkFiles set, each file is checked for changes so the returned
+// metadata is current; otherwise only the listing is, which suffices for the
+// ID, path and project directory since those never change.
+func (c *sessionCache) sessions(dir string, checkFiles bool) ([]cachedSession, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stat, err := os.Stat(dir)
+	if err != nil {
+		if _, ok := c.Dirs[dir]; ok {
+			delete(c.Dirs, dir)
+			c.dirty = true
+		}
+		return nil, err
+	}
+	modTime := stat.ModTime().UnixNano()
+
+	cached := c.Dirs[dir]
+	if cached != nil && cached.ModTime == modTime && time.Duration(cached.Scanned-modTime) > racyWindow {
+		if checkFiles {
+			for sessionID, sess := range cached.Sessions {
+				if !c.fileCurrent(sess) {
+					c.reload(cached, sessionID, sess.Path)
+				}
+			}
+		}
+		return sortedSessions(cached.Sessions), nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &cachedDir{
+		ModTime:  modTime,
+		Scanned:  time.Now().UnixNano(),
+		Sessions: make(map[string]*cachedSession, len(entries)),
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		sessionID := strings.TrimSuffix(entry.Name(), ".json")
+
+		if cached != nil {
+			if sess, ok := cached.Sessions[sessionID]; ok && (!checkFiles || c.fileCurrent(sess)) {
+				listing.Sessions[sessionID] = sess
+				continue
+			}
+		}
+		c.reload(listing, sessionID, filepath.Join(dir, entry.Name()))
+	}
+
+	c.Dirs[dir] = listing
+	c.dirty = true
+
+	return sortedSessions(listing.Sessions), nil
+}
+
+// reload reads a session's info file into listing. A file that cannot be
+// read is left out, and the listing is marked stale so the next call retries.
+func (c *sessionCache) reload(listing *cachedDir, sessionID, path string) {
+	c.dirty = true
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		delete(listing.Sessions, sessionID)
+		listing.ModTime = 0
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		delete(listing.Sessions, sessionID)
+		listing.ModTime = 0
+		return
+	}
+
+	// SessionInfo decodes itself, so the directory is read on its own rather
+	// than through a struct embedding it
+	var info SessionInfo
+	var location struct {
+		Directory string `json:"directory"`
+	}
+	if json.Unmarshal(data, &info) != nil || json.Unmarshal(data, &location) != nil {
+		delete(listing.Sessions, sessionID)
+		listing.ModTime = 0
+		return
+	}
+	info.Raw = data
+
+	listing.Sessions[sessionID] = &cachedSession{
+		ID:        sessionID,
+		Path:      path,
+		Directory: location.Directory,
+		Info:      info,
+		ModTime:   stat.ModTime().UnixNano(),
+		Size:      stat.Size(),
+	}
+}
+
+// fileCurrent reports whether a session's info file is unchanged
+func (c *sessionCache) fileCurrent(sess *cachedSession) bool {
+	stat, err := os.Stat(sess.Path)
+	return err == nil && stat.ModTime().UnixNano() == sess.ModTime && stat.Size() == sess.Size
+}
+
+// info returns the cached metadata of the session stored at path, if the file
+// is unchanged since it was cached
+func (c *sessionCache) info(path string) (*SessionInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	listing := c.Dirs[filepath.Dir(path)]
+	if listing == nil {
+		return nil, false
+	}
+	sessionID := strings.TrimSuffix(filepath.Base(path), ".json")
+	sess, ok := listing.Sessions[sessionID]
+	if !ok || sess.Path != path || !c.fileCurrent(sess) {
+		return nil, false
+	}
+
+	info := sess.Info
+	return &info, true
+}
+
+// lookup returns the info file of a session found in any cached directory
+// under root, if it still exists
+func (c *sessionCache) lookup(root, sessionID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for dir, listing := range c.Dirs {
+		if filepath.Dir(dir) != root {
+			continue
+		}
+		if sess, ok := listing.Sessions[sessionID]; ok {
+			if _, err := os.Stat(sess.Path); err == nil {
+				return sess.Path, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// save writes the cache if it changed. Failures are ignored, since the cache
+// is rebuilt from storage whenever it is missing.
+func (c *sessionCache) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty || c.path == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), SessionCacheFile+".*.tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	c.Version = sessionCacheVersion
+	if err := gob.NewEncoder(tmp).Encode(c); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return
+	}
+
+	c.dirty = false
+}
+
+// sortedSessions copies a listing ordered by session ID, so callers can use
+// it without holding the lock
+func sortedSessions(sessions map[string]*cachedSession) []cachedSession {
+	ids := make([]string, 0, len(sessions))
+	for sessionID := range sessions {
+		ids = append(ids, sessionID)
+	}
+	sort.Strings(ids)
+
+	sorted := make([]cachedSession, 0, len(ids))
+	for _, sessionID := range ids {
+		sorted = append(sorted, *sessions[sessionID])
+	}
+	return sorted
+}
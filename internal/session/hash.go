@@ -0,0 +1,159 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var _ writableStorage = (*hashStorage)(nil)
+
+// hashStorage reads the layout current opencode versions use, which keeps
+// every project's sessions in one store, grouped by project ID:
+//
+//	storage/session/<projectID>/<sessionID>.json
+//	storage/message/<sessionID>/<messageID>.json
+//	storage/part/<messageID>/<partID>.json
+type hashStorage struct {
+	dir string // storage
+	// directory limits the store to sessions started in this project
+	// directory; empty for all sessions
+	directory string
+	// projectID is the ID of directory's project, which sessions written to
+	// the store are filed under; only set for writing
+	projectID string
+}
+
+func (s *hashStorage) Layout() Layout {
+	return LayoutHash
+}
+
+func (s *hashStorage) Sessions(fresh bool) ([]StoredSession, error) {
+	sessionDir := filepath.Join(s.dir, "session")
+
+	projectDirs, err := os.ReadDir(sessionDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	var stored []StoredSession
+	for _, projectDir := range projectDirs {
+		if !projectDir.IsDir() {
+			continue
+		}
+
+		// The cache records each session's directory, so only new session
+		// files have to be opened to check which project they belong to
+		sessions, err := loadCache().sessions(filepath.Join(sessionDir, projectDir.Name()), fresh)
+		if err != nil {
+			continue
+		}
+
+		var project *projectInfo
+		projectRead := false
+
+		for _, sess := range sessions {
+			if s.directory != "" && sess.Directory != s.directory {
+				continue
+			}
+			if !projectRead {
+				project = readProjectInfo(s.dir, projectDir.Name())
+				projectRead = true
+			}
+			name, path := resolveProject(project, projectDir.Name(), sess.Directory)
+			stored = append(stored, StoredSession{
+				ID:          sess.ID,
+				Project:     name,
+				ProjectPath: path,
+				Directory:   sess.Directory,
+				InfoPath:    sess.Path,
+				Info:        sess.Info,
+			})
+		}
+	}
+
+	return stored, nil
+}
+
+// InfoPath finds the project directory holding the session file
+func (s *hashStorage) InfoPath(sessionID string) (string, error) {
+	sessionDir := filepath.Join(s.dir, "session")
+
+	if path, ok := loadCache().lookup(sessionDir, sessionID); ok {
+		return path, nil
+	}
+
+	projectDirs, err := os.ReadDir(sessionDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	for _, projectDir := range projectDirs {
+		if !projectDir.IsDir() {
+			continue
+		}
+
+		sessionPath := filepath.Join(sessionDir, projectDir.Name(), sessionID+".json")
+		if _, err := os.Stat(sessionPath); err == nil {
+			return sessionPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("session %s not found", sessionID)
+}
+
+func (s *hashStorage) MessageDir(sessionID string) string {
+	return filepath.Join(s.dir, "message", sessionID)
+}
+
+// PartRoot returns the top-level part directory, which is shared by all
+// sessions since part directories are keyed by message ID alone
+func (s *hashStorage) PartRoot(sessionID string) string {
+	return filepath.Join(s.dir, "part")
+}
+
+func (s *hashStorage) PartDir(sessionID, messageID string) string {
+	return filepath.Join(s.dir, "part", messageID)
+}
+
+// SnapshotDir returns snapshot/<projectID>, where the project ID is the name
+// of the directory holding the session file
+func (s *hashStorage) SnapshotDir(sessionID string) (string, error) {
+	infoPath, err := s.InfoPath(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return s.snapshotDir(filepath.Base(filepath.Dir(infoPath))), nil
+}
+
+func (s *hashStorage) snapshotDir(projectID string) string {
+	return filepath.Join(filepath.Dir(s.dir), "snapshot", projectID)
+}
+
+func (s *hashStorage) root() string {
+	return s.dir
+}
+
+func (s *hashStorage) newInfoPath(sessionID string) string {
+	return filepath.Join(s.dir, "session", s.projectID, sessionID+".json")
+}
+
+// infoPaths also finds info files under other projects' directories, which
+// would otherwise leave a second session with the same ID behind
+func (s *hashStorage) infoPaths(sessionID string) ([]string, error) {
+	return filepath.Glob(filepath.Join(s.dir, "session", "*", sessionID+".json"))
+}
+
+// adoptInfo files the session under the store's project and directory
+func (s *hashStorage) adoptInfo(fields map[string]interface{}) bool {
+	fields["directory"] = s.directory
+	fields["projectID"] = s.projectID
+	return true
+}
+
+func (s *hashStorage) projectSnapshotDir() string {
+	return s.snapshotDir(s.projectID)
+}
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var _ writableStorage = (*legacyStorage)(nil)
+
+// legacyStorage reads the layout opencode used first, which keeps each
+// project's sessions in its own directory:
+//
+//	project/<name>/storage/session/info/<sessionID>.json
+//	project/<name>/storage/session/message/<sessionID>/<messageID>.json
+//	project/<name>/storage/session/part/<sessionID>/<messageID>/<partID>.json
+type legacyStorage struct {
+	dir     string // project/<name>/storage
+	project string
+}
+
+func newLegacyStorage(dir string) *legacyStorage {
+	return &legacyStorage{dir: dir, project: filepath.Base(filepath.Dir(dir))}
+}
+
+func (s *legacyStorage) Layout() Layout {
+	return LayoutLegacy
+}
+
+func (s *legacyStorage) Sessions(fresh bool) ([]StoredSession, error) {
+	sessions, err := loadCache().sessions(filepath.Join(s.dir, "session", "info"), fresh)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session info directory: %w", err)
+	}
+
+	stored := make([]StoredSession, 0, len(sessions))
+	for _, sess := range sessions {
+		stored = append(stored, StoredSession{
+			ID:        sess.ID,
+			Project:   s.project,
+			Directory: sess.Directory,
+			InfoPath:  sess.Path,
+			Info:      sess.Info,
+		})
+	}
+
+	return stored, nil
+}
+
+func (s *legacyStorage) InfoPath(sessionID string) (string, error) {
+	path := s.newInfoPath(sessionID)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("session %s not found", sessionID)
+	}
+	return path, nil
+}
+
+func (s *legacyStorage) MessageDir(sessionID string) string {
+	return filepath.Join(s.dir, "session", "message", sessionID)
+}
+
+func (s *legacyStorage) PartRoot(sessionID string) string {
+	return filepath.Join(s.dir, "session", "part", sessionID)
+}
+
+func (s *legacyStorage) PartDir(sessionID, messageID string) string {
+	return filepath.Join(s.PartRoot(sessionID), messageID)
+}
+
+// SnapshotDir returns the snapshot repository next to the project's storage
+func (s *legacyStorage) SnapshotDir(sessionID string) (string, error) {
+	return s.projectSnapshotDir(), nil
+}
+
+func (s *legacyStorage) root() string {
+	return s.dir
+}
+
+func (s *legacyStorage) newInfoPath(sessionID string) string {
+	return filepath.Join(s.dir, "session", "info", sessionID+".json")
+}
+
+func (s *legacyStorage) infoPaths(sessionID string) ([]string, error) {
+	return []string{s.newInfoPath(sessionID)}, nil
+}
+
+// adoptInfo changes nothing, as the project is the directory the storage is in
+func (s *legacyStorage) adoptInfo(fields map[string]interface{}) bool {
+	return false
+}
+
+func (s *legacyStorage) projectSnapshotDir() string {
+	return filepath.Join(filepath.Dir(s.dir), "snapshot")
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// SetJobs sets how many storage files the reader reads at once. The limit
+// holds across every goroutine using the reader, including readers for other
+// projects created by ListAllSessions, so callers may load several sessions
+// in parallel without multiplying it. Values below two read sequentially.
+func (r *Reader) SetJobs(n int) {
+	if n < 2 {
+		r.jobs = 1
+		r.sem = nil
+		return
+	}
+	r.jobs = n
+	r.sem = make(chan struct{}, n)
+}
+
+// Jobs returns the concurrency set with SetJobs
+func (r *Reader) Jobs() int {
+	return max(r.jobs, 1)
+}
+
+// forEach calls fn for every index below count, on up to Jobs goroutines at
+// once, and returns when all calls have finished
+func (r *Reader) forEach(count int, fn func(i int)) {
+	workers := min(r.Jobs(), count)
+	if workers <= 1 {
+		for i := 0; i < count; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= count {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// readFile reads a storage file, waiting for a free slot when reads are limited
+func (r *Reader) readFile(path string) ([]byte, error) {
+	if r.sem != nil {
+		r.sem <- struct{}{}
+		defer func() { <-r.sem }()
+	}
+	return os.ReadFile(path)
+}
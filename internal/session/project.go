@@ -0,0 +1,66 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectInfo is what opencode records about a project: the storage/project
+// file in the hash layout, or a row of the project table in the database
+type projectInfo struct {
+	ID       string `json:"id"`
+	Worktree string `json:"worktree"` // Git root, or "/" for the global project
+	Name     string `json:"name,omitempty"`
+}
+
+// readProjectInfo reads storage/project/<projectID>.json. Older versions did
+// not write these files, so a missing or unreadable one yields nil.
+func readProjectInfo(storageDir, projectID string) *projectInfo {
+	data, err := os.ReadFile(filepath.Join(storageDir, "project", projectID+".json"))
+	if err != nil {
+		return nil
+	}
+
+	var project projectInfo
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil
+	}
+	return &project
+}
+
+// resolveProject returns the name and path a session is listed under. The
+// path is the project's worktree when the session was started inside it, and
+// otherwise the session's own directory, since opencode files every session
+// outside a git repository under one global project. The name is the
+// project's own name if it has one, else the last element of the path, else
+// the project ID.
+func resolveProject(project *projectInfo, projectID, directory string) (name, path string) {
+	path = directory
+	if project != nil && project.Worktree != "" && !isRoot(project.Worktree) &&
+		(directory == "" || withinDir(project.Worktree, directory)) {
+		path = project.Worktree
+		if project.Name != "" {
+			return project.Name, path
+		}
+	}
+
+	if path == "" {
+		return projectID, ""
+	}
+	return filepath.Base(path), path
+}
+
+// withinDir reports whether path is dir or inside it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func isRoot(path string) bool {
+	return filepath.Dir(path) == path
+}
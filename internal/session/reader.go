@@ -7,142 +7,75 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-
-	"github.com/fantomc0der/opencode-session-export/internal/config"
 )
 
-// Reader handles reading session data from the filesystem
+// Reader handles reading session data from the filesystem. It reads through
+// one or more Storage backends, so the same calls work for every layout.
 type Reader struct {
-	storageDir  string
-	projectPath string // For filtering sessions in new format
+	stores []Storage
+
+	jobs int           // Maximum concurrent file reads; see SetJobs
+	sem  chan struct{} // Limits file reads across every goroutine using the reader
+
+	snapshots bool // Set SessionInfo.SnapshotDir; see SetSnapshots
 }
 
 // SessionWithProject represents a session with its associated project information
 type SessionWithProject struct {
 	SessionID   string
 	ProjectName string
+	ProjectPath string // Project directory, when the storage layout records it
 	Info        SessionInfo
+
+	reader *Reader
+}
+
+// Reader returns a reader for the project the session belongs to
+func (s *SessionWithProject) Reader() *Reader {
+	return s.reader
 }
 
 // NewReader creates a new session reader
 func NewReader(projectPath string) (*Reader, error) {
-	// First try the old format (project-based storage)
-	storageDir, err := config.GetStorageDir(projectPath)
+	stores, err := ProjectStorage(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get storage directory: %w", err)
-	}
-
-	// Check if old format exists
-	if _, err := os.Stat(filepath.Join(storageDir, "session")); err == nil {
-		return &Reader{
-			storageDir: storageDir,
-		}, nil
+		return nil, err
 	}
 
-	// Try new format (hash-based storage in main storage directory)
-	dataDir, err := config.GetOpencodeDataDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get opencode data directory: %w", err)
-	}
-
-	mainStorageDir := filepath.Join(dataDir, "storage")
-	return &Reader{
-		storageDir:  mainStorageDir,
-		projectPath: projectPath,
-	}, nil
+	return NewStorageReader(stores...), nil
 }
 
 // NewGlobalReader creates a new session reader for accessing all projects
 func NewGlobalReader() (*Reader, error) {
-	return &Reader{
-		storageDir: "", // Will be set dynamically for each project
-	}, nil
-}
-
-// ListSessions returns all available session IDs
-func (r *Reader) ListSessions() ([]string, error) {
-	// Check if this is the new format (hash-based storage)
-	if r.projectPath != "" {
-		return r.listSessionsNewFormat()
-	}
-
-	// Old format (project-based storage)
-	sessionInfoDir := filepath.Join(r.storageDir, "session", "info")
-
-	entries, err := os.ReadDir(sessionInfoDir)
+	stores, err := AllStorage()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil
-		}
-		return nil, fmt.Errorf("failed to read session info directory: %w", err)
+		return nil, err
 	}
 
-	var sessionIDs []string
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		if strings.HasSuffix(entry.Name(), ".json") {
-			sessionID := strings.TrimSuffix(entry.Name(), ".json")
-			sessionIDs = append(sessionIDs, sessionID)
-		}
-	}
-
-	return sessionIDs, nil
+	return NewStorageReader(stores...), nil
 }
 
-// listSessionsNewFormat lists sessions in the new hash-based format
-func (r *Reader) listSessionsNewFormat() ([]string, error) {
-	sessionDir := filepath.Join(r.storageDir, "session")
-
-	// Read all project directories (hashes)
-	projectDirs, err := os.ReadDir(sessionDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil
-		}
-		return nil, fmt.Errorf("failed to read session directory: %w", err)
-	}
+// NewStorageReader creates a reader for the given storage backends. Sessions
+// are looked up in the order the backends are given.
+func NewStorageReader(stores ...Storage) *Reader {
+	return &Reader{stores: stores}
+}
 
-	absProjectPath, _ := filepath.Abs(r.projectPath)
+// ListSessions returns all available session IDs
+func (r *Reader) ListSessions() ([]string, error) {
+	defer loadCache().save()
 
 	var sessionIDs []string
-	for _, projectDir := range projectDirs {
-		if !projectDir.IsDir() {
-			continue
-		}
-
-		// Read session files in this project directory
-		projectSessionDir := filepath.Join(sessionDir, projectDir.Name())
-		sessionFiles, err := os.ReadDir(projectSessionDir)
+	seen := make(map[string]bool)
+	for _, store := range r.stores {
+		sessions, err := store.Sessions(false)
 		if err != nil {
-			continue
+			return nil, err
 		}
-
-		// Check each session file to see if it belongs to our project
-		for _, sessionFile := range sessionFiles {
-			if !strings.HasSuffix(sessionFile.Name(), ".json") {
-				continue
-			}
-
-			// Read the session metadata to check the directory
-			sessionPath := filepath.Join(projectSessionDir, sessionFile.Name())
-			data, err := os.ReadFile(sessionPath)
-			if err != nil {
-				continue
-			}
-
-			var sessionMeta struct {
-				ID        string `json:"id"`
-				Directory string `json:"directory"`
-			}
-			if err := json.Unmarshal(data, &sessionMeta); err != nil {
-				continue
-			}
-
-			// Check if this session belongs to our project
-			if sessionMeta.Directory == absProjectPath {
-				sessionIDs = append(sessionIDs, sessionMeta.ID)
+		for _, sess := range sessions {
+			if !seen[sess.ID] {
+				seen[sess.ID] = true
+				sessionIDs = append(sessionIDs, sess.ID)
 			}
 		}
 	}
@@ -150,53 +83,36 @@ func (r *Reader) listSessionsNewFormat() ([]string, error) {
 	return sessionIDs, nil
 }
 
-// ListAllSessions returns all sessions from all projects
+// ListAllSessions returns the sessions of every backend the reader reads,
+// which for a global reader is all sessions of all projects
 func (r *Reader) ListAllSessions() ([]SessionWithProject, error) {
-	dataDir, err := config.GetOpencodeDataDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get opencode data directory: %w", err)
-	}
-
-	projectsDir := filepath.Join(dataDir, "project")
-
-	// Check if projects directory exists
-	if _, err := os.Stat(projectsDir); os.IsNotExist(err) {
-		return []SessionWithProject{}, nil
-	}
-
-	entries, err := os.ReadDir(projectsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read projects directory: %w", err)
-	}
+	defer loadCache().save()
 
 	var allSessions []SessionWithProject
+	seen := make(map[string]bool)
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		projectName := entry.Name()
-		storageDir := filepath.Join(projectsDir, projectName, "storage")
-
-		// Create a temporary reader for this project
-		projectReader := &Reader{storageDir: storageDir}
-
-		sessionIDs, err := projectReader.ListSessions()
+	for _, store := range r.stores {
+		sessions, err := store.Sessions(true)
 		if err != nil {
 			continue // Skip projects with errors
 		}
 
-		for _, sessionID := range sessionIDs {
-			info, err := projectReader.ReadSessionInfo(sessionID)
-			if err != nil {
-				continue // Skip sessions with errors
+		// Sessions of one backend are read through a reader for it alone,
+		// sharing this reader's concurrency limit
+		storeReader := &Reader{stores: []Storage{store}, jobs: r.jobs, sem: r.sem, snapshots: r.snapshots}
+
+		for _, sess := range sessions {
+			if seen[sess.ID] {
+				continue
 			}
+			seen[sess.ID] = true
 
 			allSessions = append(allSessions, SessionWithProject{
-				SessionID:   sessionID,
-				ProjectName: projectName,
-				Info:        *info,
+				SessionID:   sess.ID,
+				ProjectName: sess.Project,
+				ProjectPath: sess.ProjectPath,
+				Info:        sess.Info,
+				reader:      storeReader,
 			})
 		}
 	}
@@ -209,20 +125,36 @@ func (r *Reader) ListAllSessions() ([]SessionWithProject, error) {
 	return allSessions, nil
 }
 
+// SetSnapshots makes the reader set SessionInfo.SnapshotDir on the sessions
+// it reads, so exports can diff the snapshots their steps recorded
+func (r *Reader) SetSnapshots(enabled bool) {
+	r.snapshots = enabled
+}
+
 // ReadSessionInfo reads session metadata
 func (r *Reader) ReadSessionInfo(sessionID string) (*SessionInfo, error) {
-	var infoPath string
+	info, err := r.readSessionInfo(sessionID)
+	if err != nil || !r.snapshots {
+		return info, err
+	}
 
-	if r.projectPath != "" {
-		// New format: find the session file
-		sessionPath, err := r.findSessionFile(sessionID)
-		if err != nil {
-			return nil, err
-		}
-		infoPath = sessionPath
-	} else {
-		// Old format
-		infoPath = filepath.Join(r.storageDir, "session", "info", sessionID+".json")
+	// A session whose project has no snapshots is still exported
+	info.SnapshotDir, _ = r.SnapshotDir(sessionID)
+	return info, nil
+}
+
+func (r *Reader) readSessionInfo(sessionID string) (*SessionInfo, error) {
+	store, infoPath, err := r.locate(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if records, ok := store.(RecordStorage); ok {
+		return records.ReadInfo(sessionID)
+	}
+
+	if info, ok := loadCache().info(infoPath); ok {
+		return info, nil
 	}
 
 	data, err := os.ReadFile(infoPath)
@@ -234,134 +166,216 @@ func (r *Reader) ReadSessionInfo(sessionID string) (*SessionInfo, error) {
 	if err := json.Unmarshal(data, &info); err != nil {
 		return nil, fmt.Errorf("failed to parse session info: %w", err)
 	}
+	info.Raw = data
 
 	return &info, nil
 }
 
-// findSessionFile finds a session file in the new format
-func (r *Reader) findSessionFile(sessionID string) (string, error) {
-	sessionDir := filepath.Join(r.storageDir, "session")
-
-	// Search through all project directories
-	projectDirs, err := os.ReadDir(sessionDir)
-	if err != nil {
-		return "", fmt.Errorf("failed to read session directory: %w", err)
+// locate returns the backend holding a session and the session's info file
+func (r *Reader) locate(sessionID string) (Storage, string, error) {
+	err := fmt.Errorf("session %s not found", sessionID)
+	for _, store := range r.stores {
+		path, storeErr := store.InfoPath(sessionID)
+		if storeErr == nil {
+			return store, path, nil
+		}
+		err = storeErr
 	}
+	return nil, "", err
+}
 
-	for _, projectDir := range projectDirs {
-		if !projectDir.IsDir() {
-			continue
-		}
+// InfoPath returns the file holding a session's metadata; see Storage.InfoPath
+func (r *Reader) InfoPath(sessionID string) (string, error) {
+	_, infoPath, err := r.locate(sessionID)
+	return infoPath, err
+}
 
-		// Look for the session file
-		sessionPath := filepath.Join(sessionDir, projectDir.Name(), sessionID+".json")
-		if _, err := os.Stat(sessionPath); err == nil {
-			return sessionPath, nil
-		}
+// storage returns the backend holding a session. A reader with a single
+// backend uses it without checking that the session exists.
+func (r *Reader) storage(sessionID string) (Storage, error) {
+	if len(r.stores) == 1 {
+		return r.stores[0], nil
 	}
+	store, _, err := r.locate(sessionID)
+	return store, err
+}
 
-	return "", fmt.Errorf("session %s not found", sessionID)
+// fileStorage returns store as a FileStorage, or ErrNotFileStorage
+func fileStorage(store Storage) (FileStorage, error) {
+	files, ok := store.(FileStorage)
+	if !ok {
+		return nil, ErrNotFileStorage
+	}
+	return files, nil
 }
 
 // ReadMessages reads all messages for a session
 func (r *Reader) ReadMessages(sessionID string) ([]Message, error) {
-	var messageDir string
+	store, err := r.storage(sessionID)
+	if err != nil {
+		return nil, err
+	}
 
-	if r.projectPath != "" {
-		// New format: messages are in session-specific subdirectory
-		messageDir = filepath.Join(r.storageDir, "message", sessionID)
-	} else {
-		// Old format
-		messageDir = filepath.Join(r.storageDir, "session", "message", sessionID)
+	if records, ok := store.(RecordStorage); ok {
+		messages, err := records.ReadMessages(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		SortMessages(messages)
+		return messages, nil
 	}
 
-	entries, err := os.ReadDir(messageDir)
+	files, err := fileStorage(store)
+	if err != nil {
+		return nil, err
+	}
+	messageDir := files.MessageDir(sessionID)
+
+	names, err := listJSONFiles(messageDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []Message{}, nil
-		}
 		return nil, fmt.Errorf("failed to read message directory: %w", err)
 	}
 
-	var messages []Message
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	// Each file is decoded into its own slot so the result does not depend
+	// on which read finishes first
+	slots := make([]*Message, len(names))
+	r.forEach(len(names), func(i int) {
+		data, err := r.readFile(filepath.Join(messageDir, names[i]+".json"))
+		if err != nil {
+			return // Skip corrupted files
 		}
-		if strings.HasSuffix(entry.Name(), ".json") {
-			messagePath := filepath.Join(messageDir, entry.Name())
-			data, err := os.ReadFile(messagePath)
-			if err != nil {
-				continue // Skip corrupted files
-			}
-
-			var message Message
-			if err := json.Unmarshal(data, &message); err != nil {
-				continue // Skip corrupted files
-			}
+		slots[i], _ = decodeMessage(data)
+	})
 
-			messages = append(messages, message)
+	var messages []Message
+	for _, message := range slots {
+		if message != nil {
+			messages = append(messages, *message)
 		}
 	}
 
-	// Sort messages by creation time
-	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].GetCreatedAt().Before(messages[j].GetCreatedAt())
-	})
+	SortMessages(messages)
 
 	return messages, nil
 }
 
 // ReadMessageParts reads all parts for a specific message
 func (r *Reader) ReadMessageParts(sessionID, messageID string) ([]MessagePart, error) {
-	var partDir string
+	store, err := r.storage(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if records, ok := store.(RecordStorage); ok {
+		parts, err := records.ReadParts(sessionID, messageID)
+		if err != nil {
+			return nil, err
+		}
+		SortParts(parts)
+		return parts, nil
+	}
 
-	if r.projectPath != "" {
-		// New format: parts are in top-level part directory under messageID
-		partDir = filepath.Join(r.storageDir, "part", messageID)
-	} else {
-		// Old format
-		partDir = filepath.Join(r.storageDir, "session", "part", sessionID, messageID)
+	files, err := fileStorage(store)
+	if err != nil {
+		return nil, err
 	}
+	partDir := files.PartDir(sessionID, messageID)
 
-	entries, err := os.ReadDir(partDir)
+	names, err := listJSONFiles(partDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return []MessagePart{}, nil
-		}
 		return nil, fmt.Errorf("failed to read part directory: %w", err)
 	}
 
+	slots := make([]*MessagePart, len(names))
+	r.forEach(len(names), func(i int) {
+		data, err := r.readFile(filepath.Join(partDir, names[i]+".json"))
+		if err != nil {
+			return // Skip corrupted files
+		}
+		slots[i], _ = decodePart(data)
+	})
+
 	var parts []MessagePart
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	for _, part := range slots {
+		if part != nil {
+			parts = append(parts, *part)
 		}
-		if strings.HasSuffix(entry.Name(), ".json") {
-			partPath := filepath.Join(partDir, entry.Name())
-			data, err := os.ReadFile(partPath)
-			if err != nil {
-				continue // Skip corrupted files
-			}
+	}
 
-			var part MessagePart
-			if err := json.Unmarshal(data, &part); err != nil {
-				continue // Skip corrupted files
-			}
+	SortParts(parts)
 
-			parts = append(parts, part)
-		}
+	return parts, nil
+}
+
+// ReadMessageFile decodes a single message file
+func ReadMessageFile(path string) (*Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := decodeMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message %s: %w", path, err)
 	}
 
-	// Sort parts by creation time
-	sort.Slice(parts, func(i, j int) bool {
-		return parts[i].GetCreatedAt().Before(parts[j].GetCreatedAt())
+	return message, nil
+}
+
+// ReadPartFile decodes a single part file
+func ReadPartFile(path string) (*MessagePart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	part, err := decodePart(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse part %s: %w", path, err)
+	}
+
+	return part, nil
+}
+
+func decodeMessage(data []byte) (*Message, error) {
+	var message Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, err
+	}
+	message.Raw = data
+	return &message, nil
+}
+
+func decodePart(data []byte) (*MessagePart, error) {
+	var part MessagePart
+	if err := json.Unmarshal(data, &part); err != nil {
+		return nil, err
+	}
+	part.Raw = data
+	return &part, nil
+}
+
+// SortMessages orders messages by creation time, as ReadMessages returns them.
+// Messages created at the same time keep their order.
+func SortMessages(messages []Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].GetCreatedAt().Before(messages[j].GetCreatedAt())
 	})
+}
 
-	return parts, nil
+// SortParts orders the parts of one message in the order they were created,
+// as ReadMessageParts returns them. That is the order of their IDs, which
+// opencode assigns in ascending order; part times cannot be used, since step,
+// tool and file parts have none.
+func SortParts(parts []MessagePart) {
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].ID < parts[j].ID
+	})
 }
 
-// ReadSession reads a complete session with all its data
+// ReadSession reads a complete session with all its data. With SetJobs
+// above one, message and part files are read concurrently; the result is the
+// same as reading them one by one.
 func (r *Reader) ReadSession(sessionID string) (*Session, error) {
 	info, err := r.ReadSessionInfo(sessionID)
 	if err != nil {
@@ -373,12 +387,19 @@ func (r *Reader) ReadSession(sessionID string) (*Session, error) {
 		return nil, fmt.Errorf("failed to read messages: %w", err)
 	}
 
-	var allParts []MessagePart
-	for _, message := range messages {
-		parts, err := r.ReadMessageParts(sessionID, message.ID)
+	// Read every message's parts in one pool rather than one message at a
+	// time, so sessions of many short messages also benefit
+	partsByMessage := make([][]MessagePart, len(messages))
+	r.forEach(len(messages), func(i int) {
+		parts, err := r.ReadMessageParts(sessionID, messages[i].ID)
 		if err != nil {
-			continue // Skip messages with corrupted parts
+			return // Skip messages with corrupted parts
 		}
+		partsByMessage[i] = parts
+	})
+
+	var allParts []MessagePart
+	for _, parts := range partsByMessage {
 		allParts = append(allParts, parts...)
 	}
 
@@ -388,3 +409,179 @@ func (r *Reader) ReadSession(sessionID string) (*Session, error) {
 		Parts:    allParts,
 	}, nil
 }
+
+// FileKind identifies what a raw storage file holds
+type FileKind string
+
+const (
+	FileSessionInfo FileKind = "session"
+	FileMessage     FileKind = "message"
+	FilePart        FileKind = "part"
+)
+
+// SessionFile is a raw storage file that makes up part of a session
+type SessionFile struct {
+	Kind      FileKind
+	SessionID string
+	MessageID string // Empty for session info files
+	PartID    string // Set for part files only
+	Path      string
+}
+
+// SessionFiles returns every storage file ReadSession reads for a session:
+// the info file, each message file and each part file. It fails with
+// ErrNotFileStorage for sessions kept in a database.
+func (r *Reader) SessionFiles(sessionID string) ([]SessionFile, error) {
+	located, infoPath, err := r.locate(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	store, err := fileStorage(located)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []SessionFile{{Kind: FileSessionInfo, SessionID: sessionID, Path: infoPath}}
+
+	messageDir := store.MessageDir(sessionID)
+	messageIDs, err := listJSONFiles(messageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message directory: %w", err)
+	}
+
+	for _, messageID := range messageIDs {
+		files = append(files, SessionFile{
+			Kind:      FileMessage,
+			SessionID: sessionID,
+			MessageID: messageID,
+			Path:      filepath.Join(messageDir, messageID+".json"),
+		})
+
+		partDir := store.PartDir(sessionID, messageID)
+		partIDs, err := listJSONFiles(partDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read part directory: %w", err)
+		}
+
+		for _, partID := range partIDs {
+			files = append(files, SessionFile{
+				Kind:      FilePart,
+				SessionID: sessionID,
+				MessageID: messageID,
+				PartID:    partID,
+				Path:      filepath.Join(partDir, partID+".json"),
+			})
+		}
+	}
+
+	return files, nil
+}
+
+// WatchDirs returns the directories whose entries make up a session: the
+// directory of its info file, its message directory, the directory holding
+// its per-message part directories, and each of those part directories.
+// Directories that do not exist yet are replaced by their nearest existing
+// parent so their creation can be observed. For a session kept in a database
+// it is the directory of the database and its journal files.
+func (r *Reader) WatchDirs(sessionID string) ([]string, error) {
+	located, infoPath, err := r.locate(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	store, ok := located.(FileStorage)
+	if !ok {
+		return []string{filepath.Dir(infoPath)}, nil
+	}
+
+	messageDir := store.MessageDir(sessionID)
+	candidates := []string{filepath.Dir(infoPath), messageDir, store.PartRoot(sessionID)}
+
+	messageIDs, err := listJSONFiles(messageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message directory: %w", err)
+	}
+	for _, messageID := range messageIDs {
+		candidates = append(candidates, store.PartDir(sessionID, messageID))
+	}
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, dir := range candidates {
+		dir = existingDir(dir)
+		if dir != "" && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs, nil
+}
+
+// existingDir returns dir, or its nearest ancestor that exists
+func existingDir(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// SnapshotDir returns opencode's snapshot repository for the project a
+// session belongs to, or an empty string if the project has none
+func (r *Reader) SnapshotDir(sessionID string) (string, error) {
+	store, err := r.storage(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	snapshotDir, err := store.SnapshotDir(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(snapshotDir); err != nil || !info.IsDir() {
+		return "", nil
+	}
+
+	return snapshotDir, nil
+}
+
+// listJSONFiles returns the names of the .json files in dir without their
+// extension, or nothing if dir does not exist
+func listJSONFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+
+	return names, nil
+}
+
+// Layout returns the storage layout the reader reads from, or an empty
+// layout if its backends use different ones
+func (r *Reader) Layout() Layout {
+	var layout Layout
+	for i, store := range r.stores {
+		if i > 0 && store.Layout() != layout {
+			return ""
+		}
+		layout = store.Layout()
+	}
+	return layout
+}
@@ -0,0 +1,50 @@
+package session
+
+import "encoding/json"
+
+// ReasoningText returns the text of a reasoning part
+func ReasoningText(part *MessagePart) string {
+	if part.Text != nil {
+		return *part.Text
+	}
+	var textData TextPartData
+	if json.Unmarshal(part.Data, &textData) != nil {
+		return ""
+	}
+	return textData.Text
+}
+
+// ReasoningTokens returns the reasoning tokens spent on each reasoning part
+// of a message, by part ID. opencode only counts reasoning tokens per step,
+// in the step's step-finish part, so a count is known for a reasoning part
+// that is alone in its step, or alone in a message without step parts.
+// parts must be in the order SortParts puts them in.
+func ReasoningTokens(message *Message, parts []MessagePart) map[string]int {
+	counts := make(map[string]int)
+
+	var stepReasoning []string // Reasoning parts of the current step
+	steps := false
+	for i := range parts {
+		switch parts[i].Type {
+		case "reasoning":
+			stepReasoning = append(stepReasoning, parts[i].ID)
+		case "step-start":
+			steps = true
+			stepReasoning = nil
+		case "step-finish":
+			steps = true
+			if step := ParseStepFinish(&parts[i]); len(stepReasoning) == 1 && step != nil && step.Tokens.Reasoning > 0 {
+				counts[stepReasoning[0]] = step.Tokens.Reasoning
+			}
+			stepReasoning = nil
+		}
+	}
+
+	if !steps && len(stepReasoning) == 1 && message != nil {
+		if tokens := messageTokens(message); tokens.Reasoning > 0 {
+			counts[stepReasoning[0]] = tokens.Reasoning
+		}
+	}
+
+	return counts
+}
@@ -0,0 +1,293 @@
+package session
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MessageSchema identifies the shape a message was stored in. opencode has
+// changed the shape over time; every shape decodes into the same Message.
+type MessageSchema int
+
+const (
+	// SchemaFlat has model, provider, inputTokens, outputTokens and
+	// completedAt at the top level of the message
+	SchemaFlat MessageSchema = iota + 1
+	// SchemaMetadata is the shape of early opencode versions, which kept the
+	// time, error and, for assistant messages, the model, cost and tokens
+	// under a metadata object
+	SchemaMetadata
+	// SchemaCurrent has modelID, providerID, a nested tokens object with
+	// reasoning and cache counts, time.completed, path, mode, system and
+	// error. User messages name their model in a model object.
+	SchemaCurrent
+)
+
+// String returns the schema's name
+func (s MessageSchema) String() string {
+	switch s {
+	case SchemaFlat:
+		return "flat"
+	case SchemaMetadata:
+		return "metadata"
+	case SchemaCurrent:
+		return "current"
+	default:
+		return "unknown"
+	}
+}
+
+// MessagePath is the directory an assistant message ran in
+type MessagePath struct {
+	Cwd  string `json:"cwd"`
+	Root string `json:"root"`
+}
+
+// MessageError is the error an assistant message ended with, such as the
+// user aborting it or the provider rejecting its credentials
+type MessageError struct {
+	Name    string `json:"name"`              // e.g. "MessageAbortedError"
+	Message string `json:"message,omitempty"` // The error's own description, if any
+}
+
+// errorDescriptions describes the errors opencode names
+var errorDescriptions = map[string]string{
+	"MessageAbortedError":      "Aborted",
+	"ProviderAuthError":        "Provider authentication failed",
+	"MessageOutputLengthError": "Output length limit reached",
+	"APIError":                 "API error",
+	"UnknownError":             "Error",
+}
+
+// Description describes the error in a line, e.g.
+// "Provider authentication failed: invalid API key"
+func (e *MessageError) Description() string {
+	description, ok := errorDescriptions[e.Name]
+	if !ok {
+		description = e.Name
+	}
+	if e.Message == "" || e.Message == description {
+		return description
+	}
+	return description + ": " + e.Message
+}
+
+// messageTime is the time object of a message in any schema
+type messageTime struct {
+	Created   int64  `json:"created"`
+	Completed *int64 `json:"completed,omitempty"`
+}
+
+// messageRecord holds the fields of every message schema, to find which one
+// a message uses and decode it from there
+type messageRecord struct {
+	ID        string       `json:"id"`
+	SessionID string       `json:"sessionID"`
+	Role      string       `json:"role"`
+	Time      *messageTime `json:"time"`
+
+	// Model is a string in the flat schema and an object naming the model
+	// and provider in user messages of the current one
+	Model        json.RawMessage `json:"model"`
+	Provider     *string         `json:"provider"`
+	InputTokens  *int            `json:"inputTokens"`
+	OutputTokens *int            `json:"outputTokens"`
+	CompletedAt  *int64          `json:"completedAt"`
+
+	ModelID    *string         `json:"modelID"`
+	ProviderID *string         `json:"providerID"`
+	Cost       *float64        `json:"cost"`
+	Tokens     *StepTokens     `json:"tokens"`
+	Path       *MessagePath    `json:"path"`
+	Mode       *string         `json:"mode"`
+	Agent      *string         `json:"agent"`
+	System     json.RawMessage `json:"system"`
+	Error      json.RawMessage `json:"error"`
+
+	Metadata *struct {
+		SessionID string          `json:"sessionID"`
+		Time      *messageTime    `json:"time"`
+		Error     json.RawMessage `json:"error"`
+		Assistant *struct {
+			ModelID    string          `json:"modelID"`
+			ProviderID string          `json:"providerID"`
+			Cost       *float64        `json:"cost"`
+			Tokens     *StepTokens     `json:"tokens"`
+			Path       *MessagePath    `json:"path"`
+			System     json.RawMessage `json:"system"`
+		} `json:"assistant"`
+	} `json:"metadata"`
+}
+
+// UnmarshalJSON decodes a message stored in any of opencode's schemas
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var record messageRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	*m = Message{ID: record.ID, SessionID: record.SessionID, Role: record.Role}
+
+	switch {
+	case record.Metadata != nil && (record.Metadata.Time != nil || record.Metadata.Assistant != nil):
+		m.decodeMetadata(&record)
+	case record.ModelID != nil || record.ProviderID != nil || record.Tokens != nil ||
+		record.Path != nil || isObject(record.Model):
+		m.decodeCurrent(&record)
+	default:
+		m.decodeFlat(&record)
+	}
+
+	// Every schema has the totals of the token counts
+	if m.Tokens != nil {
+		m.InputTokens = &m.Tokens.Input
+		m.OutputTokens = &m.Tokens.Output
+	}
+
+	return nil
+}
+
+func (m *Message) decodeFlat(record *messageRecord) {
+	m.Schema = SchemaFlat
+	m.setTime(record.Time)
+	json.Unmarshal(record.Model, &m.Model)
+	m.Provider = record.Provider
+	m.Cost = record.Cost
+	m.Path = record.Path
+	m.Mode = record.Mode
+	m.System = decodeSystem(record.System)
+	m.Error = decodeError(record.Error)
+	if record.CompletedAt != nil {
+		m.CompletedAt = record.CompletedAt
+	}
+
+	if record.InputTokens != nil || record.OutputTokens != nil {
+		m.Tokens = &StepTokens{}
+		if record.InputTokens != nil {
+			m.Tokens.Input = *record.InputTokens
+		}
+		if record.OutputTokens != nil {
+			m.Tokens.Output = *record.OutputTokens
+		}
+	}
+}
+
+func (m *Message) decodeMetadata(record *messageRecord) {
+	m.Schema = SchemaMetadata
+	metadata := record.Metadata
+	if m.SessionID == "" {
+		m.SessionID = metadata.SessionID
+	}
+	m.setTime(metadata.Time)
+	m.Error = decodeError(metadata.Error)
+
+	if assistant := metadata.Assistant; assistant != nil {
+		m.Model = nonEmpty(assistant.ModelID)
+		m.Provider = nonEmpty(assistant.ProviderID)
+		m.Cost = assistant.Cost
+		m.Tokens = assistant.Tokens
+		m.Path = assistant.Path
+		m.System = decodeSystem(assistant.System)
+	}
+}
+
+func (m *Message) decodeCurrent(record *messageRecord) {
+	m.Schema = SchemaCurrent
+	m.setTime(record.Time)
+	m.Model = record.ModelID
+	m.Provider = record.ProviderID
+	m.Cost = record.Cost
+	m.Tokens = record.Tokens
+	m.Path = record.Path
+	m.System = decodeSystem(record.System)
+	m.Error = decodeError(record.Error)
+
+	m.Mode = record.Mode
+	if m.Mode == nil {
+		m.Mode = record.Agent
+	}
+
+	// User messages name the model they were sent to
+	var model struct {
+		ModelID    string `json:"modelID"`
+		ProviderID string `json:"providerID"`
+	}
+	if isObject(record.Model) && json.Unmarshal(record.Model, &model) == nil {
+		if m.Model == nil {
+			m.Model = nonEmpty(model.ModelID)
+		}
+		if m.Provider == nil {
+			m.Provider = nonEmpty(model.ProviderID)
+		}
+	}
+
+	// A Message encoded through its own field tags is read back in this
+	// schema, keeping the fields named as in the flat one
+	if m.Model == nil && !isObject(record.Model) {
+		json.Unmarshal(record.Model, &m.Model)
+	}
+	if m.Provider == nil {
+		m.Provider = record.Provider
+	}
+	if m.CompletedAt == nil {
+		m.CompletedAt = record.CompletedAt
+	}
+}
+
+func (m *Message) setTime(t *messageTime) {
+	if t == nil {
+		return
+	}
+	m.Time = &TimeInfo{Created: t.Created}
+	if t.Completed != nil {
+		m.CompletedAt = t.Completed
+	}
+}
+
+// decodeError decodes an error opencode recorded as {"name", "data"}, where
+// data may hold a message. It returns nil if there is no error.
+func decodeError(data json.RawMessage) *MessageError {
+	if !isObject(data) {
+		return nil
+	}
+	var recorded struct {
+		Name string `json:"name"`
+		Data struct {
+			Message string `json:"message"`
+		} `json:"data"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &recorded) != nil || recorded.Name == "" {
+		return nil
+	}
+
+	message := recorded.Data.Message
+	if message == "" {
+		message = recorded.Message
+	}
+	return &MessageError{Name: recorded.Name, Message: message}
+}
+
+// decodeSystem decodes a system prompt recorded as a string or a list of them
+func decodeSystem(data json.RawMessage) []string {
+	var prompts []string
+	if json.Unmarshal(data, &prompts) == nil {
+		return prompts
+	}
+	var prompt string
+	if json.Unmarshal(data, &prompt) == nil && prompt != "" {
+		return []string{prompt}
+	}
+	return nil
+}
+
+func isObject(data json.RawMessage) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), "{")
+}
+
+func nonEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
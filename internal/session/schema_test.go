@@ -0,0 +1,202 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMessageUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		schema MessageSchema
+		want   Message
+	}{
+		{
+			name:   "current assistant",
+			data:   `{"id":"msg_a","sessionID":"ses_a","role":"assistant","time":{"created":1,"completed":5},"modelID":"m","providerID":"p","cost":0.1,"tokens":{"input":3,"output":4,"reasoning":1,"cache":{"read":2,"write":0}},"path":{"cwd":"/x","root":"/"},"mode":"build","system":["s"],"error":{"name":"APIError","data":{"message":"boom"}}}`,
+			schema: SchemaCurrent,
+			want: Message{
+				ID: "msg_a", SessionID: "ses_a", Role: "assistant",
+				Time:         &TimeInfo{Created: 1},
+				Model:        ptr("m"),
+				Provider:     ptr("p"),
+				Cost:         ptr(0.1),
+				InputTokens:  ptr(3),
+				OutputTokens: ptr(4),
+				Tokens:       &StepTokens{Input: 3, Output: 4, Reasoning: 1, Cache: CacheTokens{Read: 2}},
+				CompletedAt:  ptr(int64(5)),
+				Path:         &MessagePath{Cwd: "/x", Root: "/"},
+				Mode:         ptr("build"),
+				System:       []string{"s"},
+				Error:        &MessageError{Name: "APIError", Message: "boom"},
+			},
+		},
+		{
+			name:   "current user with agent",
+			data:   `{"id":"msg_b","sessionID":"ses_a","role":"user","time":{"created":1},"agent":"plan","model":{"modelID":"m","providerID":"p"},"system":"one prompt"}`,
+			schema: SchemaCurrent,
+			want: Message{
+				ID: "msg_b", SessionID: "ses_a", Role: "user",
+				Time:     &TimeInfo{Created: 1},
+				Model:    ptr("m"),
+				Provider: ptr("p"),
+				Mode:     ptr("plan"),
+				System:   []string{"one prompt"},
+			},
+		},
+		{
+			name:   "flat",
+			data:   `{"id":"msg_c","sessionID":"ses_a","role":"assistant","time":{"created":1},"model":"m","provider":"p","inputTokens":3,"outputTokens":4,"completedAt":9}`,
+			schema: SchemaFlat,
+			want: Message{
+				ID: "msg_c", SessionID: "ses_a", Role: "assistant",
+				Time:         &TimeInfo{Created: 1},
+				Model:        ptr("m"),
+				Provider:     ptr("p"),
+				InputTokens:  ptr(3),
+				OutputTokens: ptr(4),
+				Tokens:       &StepTokens{Input: 3, Output: 4},
+				CompletedAt:  ptr(int64(9)),
+			},
+		},
+		{
+			name:   "metadata",
+			data:   `{"id":"msg_d","role":"assistant","metadata":{"sessionID":"ses_a","time":{"created":1,"completed":2},"error":{"name":"MessageAbortedError"},"assistant":{"modelID":"m","providerID":"p","cost":1,"tokens":{"input":1,"output":2,"reasoning":0,"cache":{"read":0,"write":0}},"path":{"cwd":"/x","root":"/"},"system":["s"]}}}`,
+			schema: SchemaMetadata,
+			want: Message{
+				ID: "msg_d", SessionID: "ses_a", Role: "assistant",
+				Time:         &TimeInfo{Created: 1},
+				Model:        ptr("m"),
+				Provider:     ptr("p"),
+				Cost:         ptr(1.0),
+				InputTokens:  ptr(1),
+				OutputTokens: ptr(2),
+				Tokens:       &StepTokens{Input: 1, Output: 2},
+				CompletedAt:  ptr(int64(2)),
+				Path:         &MessagePath{Cwd: "/x", Root: "/"},
+				System:       []string{"s"},
+				Error:        &MessageError{Name: "MessageAbortedError"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Message
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if got.Schema != tt.schema {
+				t.Errorf("Schema = %v, want %v", got.Schema, tt.schema)
+			}
+			got.Schema = 0
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+	}{
+		{
+			// Tokens make it decode through the current schema
+			name: "tokens",
+			message: Message{
+				ID: "msg_a", SessionID: "ses_a", Role: "assistant",
+				Time:         &TimeInfo{Created: 1},
+				Model:        ptr("m"),
+				Provider:     ptr("p"),
+				Cost:         ptr(0.1),
+				InputTokens:  ptr(3),
+				OutputTokens: ptr(4),
+				Tokens:       &StepTokens{Input: 3, Output: 4, Reasoning: 1, Cache: CacheTokens{Read: 2, Write: 1}},
+				CompletedAt:  ptr(int64(5)),
+				Path:         &MessagePath{Cwd: "/x", Root: "/"},
+				Mode:         ptr("build"),
+				System:       []string{"s"},
+				Error:        &MessageError{Name: "APIError", Message: "boom"},
+			},
+		},
+		{
+			// Without tokens, path or modelID it decodes through the flat schema
+			name: "no tokens",
+			message: Message{
+				ID: "msg_b", SessionID: "ses_a", Role: "assistant",
+				Time:        &TimeInfo{Created: 1},
+				Model:       ptr("m"),
+				Provider:    ptr("p"),
+				Cost:        ptr(0.2),
+				CompletedAt: ptr(int64(7)),
+				Mode:        ptr("plan"),
+				System:      []string{"a", "b"},
+				Error:       &MessageError{Name: "MessageAbortedError"},
+			},
+		},
+		{
+			name: "path without tokens",
+			message: Message{
+				ID: "msg_c", SessionID: "ses_a", Role: "assistant",
+				Path: &MessagePath{Cwd: "/x", Root: "/"},
+				Mode: ptr("build"),
+			},
+		},
+		{
+			name: "user",
+			message: Message{
+				ID: "msg_d", SessionID: "ses_a", Role: "user",
+				Time: &TimeInfo{Created: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.message)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			var got Message
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			got.Schema = 0
+			if !reflect.DeepEqual(got, tt.message) {
+				t.Errorf("round trip of %s\ngot  %+v\nwant %+v", data, got, tt.message)
+			}
+		})
+	}
+
+	// Messages decoded from every schema survive being encoded again
+	for _, data := range []string{
+		`{"id":"msg_e","sessionID":"ses_a","role":"assistant","time":{"created":1,"completed":5},"modelID":"m","providerID":"p","tokens":{"input":3,"output":4,"reasoning":0,"cache":{"read":0,"write":0}},"mode":"build"}`,
+		`{"id":"msg_f","sessionID":"ses_a","role":"user","time":{"created":1},"model":{"modelID":"m","providerID":"p"}}`,
+		`{"id":"msg_g","sessionID":"ses_a","role":"assistant","time":{"created":1},"model":"m","provider":"p","inputTokens":3,"completedAt":9}`,
+		`{"id":"msg_h","role":"assistant","metadata":{"sessionID":"ses_a","time":{"created":1,"completed":2},"assistant":{"modelID":"m","providerID":"p","cost":1,"system":["s"]}}}`,
+	} {
+		var decoded, got Message
+		if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+			t.Fatalf("Unmarshal %s: %v", data, err)
+		}
+		encoded, err := json.Marshal(decoded)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if err := json.Unmarshal(encoded, &got); err != nil {
+			t.Fatalf("Unmarshal %s: %v", encoded, err)
+		}
+		decoded.Schema, got.Schema = 0, 0
+		if !reflect.DeepEqual(got, decoded) {
+			t.Errorf("round trip of %s\ngot  %+v\nwant %+v", data, got, decoded)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package session
+
+import "encoding/json"
+
+// PatchPart is a patch part. opencode writes one after a step that changed
+// files, naming them and the snapshot taken as the step began.
+type PatchPart struct {
+	Hash  string   `json:"hash"`
+	Files []string `json:"files"`
+}
+
+// ParsePatch decodes a patch part. It returns nil for other parts.
+func ParsePatch(part *MessagePart) *PatchPart {
+	if part.Type != "patch" {
+		return nil
+	}
+	var patch PatchPart
+	if json.Unmarshal(part.Raw, &patch) != nil {
+		return nil
+	}
+	return &patch
+}
+
+// ParseSnapshot returns the snapshot hash a snapshot part records, or "" for
+// other parts
+func ParseSnapshot(part *MessagePart) string {
+	if part.Type != "snapshot" {
+		return ""
+	}
+	var snapshot struct {
+		Snapshot string `json:"snapshot"`
+	}
+	json.Unmarshal(part.Raw, &snapshot)
+	return snapshot.Snapshot
+}
+
+// PatchEnds returns the snapshot each patch part's changes end at, by part
+// ID. opencode writes a patch right after the step-finish part of the step
+// that made it, so that step's snapshot is the end. parts must be in the
+// order SortParts puts them in.
+func PatchEnds(parts []MessagePart) map[string]string {
+	ends := make(map[string]string)
+
+	finished := "" // Snapshot of the last finished step
+	for i := range parts {
+		switch parts[i].Type {
+		case "step-start":
+			finished = ""
+		case "step-finish":
+			if step := ParseStepFinish(&parts[i]); step != nil {
+				finished = step.Snapshot
+			}
+		case "patch":
+			if finished != "" {
+				ends[parts[i].ID] = finished
+			}
+		}
+	}
+
+	return ends
+}
@@ -0,0 +1,362 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	// Pure Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// DatabaseFile is the name of opencode's database in its data directory
+const DatabaseFile = "opencode.db"
+
+var _ RecordStorage = (*sqliteStorage)(nil)
+
+// sqliteStorage reads the database newer opencode versions keep sessions in.
+// The session, message and part tables hold one row per record; messages and
+// parts keep their fields as a JSON object in the data column, without the
+// IDs that have columns of their own.
+//
+// The database is opened read-only, since opencode may be writing to it.
+type sqliteStorage struct {
+	path string
+	// directory limits the store to sessions started in this project
+	// directory; empty for all sessions
+	directory string
+
+	openOnce sync.Once
+	db       *sql.DB
+	openErr  error
+}
+
+// database opens the database on first use
+func (s *sqliteStorage) database() (*sql.DB, error) {
+	s.openOnce.Do(func() {
+		// SQLite wants file:///C:/... for Windows drive paths
+		path := filepath.ToSlash(s.path)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		dsn := (&url.URL{
+			Scheme:   "file",
+			Path:     path,
+			RawQuery: "mode=ro&_pragma=busy_timeout(5000)&_pragma=query_only(1)",
+		}).String()
+
+		s.db, s.openErr = sql.Open("sqlite", dsn)
+		if s.openErr == nil {
+			s.openErr = s.db.Ping()
+		}
+		if s.openErr != nil {
+			s.openErr = fmt.Errorf("failed to open %s: %w", s.path, s.openErr)
+		}
+	})
+	return s.db, s.openErr
+}
+
+func (s *sqliteStorage) Layout() Layout {
+	return LayoutSQLite
+}
+
+// sessionColumns are the columns of the session table that map to SessionInfo
+const sessionColumns = "id, project_id, parent_id, directory, title, version, share_url, time_created, time_updated"
+
+// sessionRecord is a session row, encoded in the shape of a session info file
+type sessionRecord struct {
+	ID        string        `json:"id"`
+	ProjectID string        `json:"projectID"`
+	Directory string        `json:"directory"`
+	ParentID  *string       `json:"parentID,omitempty"`
+	Title     string        `json:"title"`
+	Version   string        `json:"version"`
+	Share     *sessionShare `json:"share,omitempty"`
+	Time      TimeInfo      `json:"time"`
+}
+
+type sessionShare struct {
+	URL string `json:"url"`
+}
+
+func (s *sqliteStorage) Sessions(fresh bool) ([]StoredSession, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+
+	projects := s.projects(db)
+
+	query := "SELECT " + sessionColumns + " FROM session"
+	var args []any
+	if s.directory != "" {
+		query += " WHERE directory = ?"
+		args = append(args, s.directory)
+	}
+	query += " ORDER BY id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var stored []StoredSession
+	for rows.Next() {
+		record, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		info, err := record.info()
+		if err != nil {
+			return nil, err
+		}
+		name, path := resolveProject(projects[record.ProjectID], record.ProjectID, record.Directory)
+		stored = append(stored, StoredSession{
+			ID:          record.ID,
+			Project:     name,
+			ProjectPath: path,
+			Directory:   record.Directory,
+			InfoPath:    s.path,
+			Info:        *info,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+
+	return stored, nil
+}
+
+// projects reads the project table by project ID. Projects only name the
+// sessions listed, so a table that cannot be read yields none.
+func (s *sqliteStorage) projects(db *sql.DB) map[string]*projectInfo {
+	projects := make(map[string]*projectInfo)
+
+	rows, err := db.Query("SELECT id, worktree, name FROM project")
+	if err != nil {
+		return projects
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var project projectInfo
+		var name sql.NullString
+		if err := rows.Scan(&project.ID, &project.Worktree, &name); err != nil {
+			continue
+		}
+		project.Name = name.String
+		projects[project.ID] = &project
+	}
+
+	return projects
+}
+
+func (s *sqliteStorage) InfoPath(sessionID string) (string, error) {
+	db, err := s.database()
+	if err != nil {
+		return "", err
+	}
+
+	var exists int
+	err = db.QueryRow("SELECT 1 FROM session WHERE id = ?", sessionID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("session %s not found", sessionID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query session %s: %w", sessionID, err)
+	}
+
+	return s.path, nil
+}
+
+func (s *sqliteStorage) ReadInfo(sessionID string) (*SessionInfo, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRow("SELECT "+sessionColumns+" FROM session WHERE id = ?", sessionID)
+	record, err := scanSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return record.info()
+}
+
+func (s *sqliteStorage) ReadMessages(sessionID string) ([]Message, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT id, time_created, data FROM message WHERE session_id = ? ORDER BY id", sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var id string
+		var created int64
+		var data []byte
+		if err := rows.Scan(&id, &created, &data); err != nil {
+			return nil, fmt.Errorf("failed to read message row: %w", err)
+		}
+
+		raw, err := recordJSON(data, map[string]string{"id": id, "sessionID": sessionID}, created)
+		if err != nil {
+			continue // Skip corrupted records, as for corrupted files
+		}
+		message, err := decodeMessage(raw)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, *message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (s *sqliteStorage) ReadParts(sessionID, messageID string) ([]MessagePart, error) {
+	db, err := s.database()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT id, time_created, data FROM part WHERE message_id = ? ORDER BY id", messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query parts: %w", err)
+	}
+	defer rows.Close()
+
+	var parts []MessagePart
+	for rows.Next() {
+		var id string
+		var created int64
+		var data []byte
+		if err := rows.Scan(&id, &created, &data); err != nil {
+			return nil, fmt.Errorf("failed to read part row: %w", err)
+		}
+
+		// Parts record when they started inside their own time object, if
+		// at all, so the row time is not filled in for them
+		raw, err := recordJSON(data, map[string]string{"id": id, "sessionID": sessionID, "messageID": messageID}, 0)
+		if err != nil {
+			continue
+		}
+		part, err := decodePart(raw)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, *part)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query parts: %w", err)
+	}
+
+	return parts, nil
+}
+
+// SnapshotDir returns snapshot/<projectID> next to the database
+func (s *sqliteStorage) SnapshotDir(sessionID string) (string, error) {
+	db, err := s.database()
+	if err != nil {
+		return "", err
+	}
+
+	var projectID string
+	err = db.QueryRow("SELECT project_id FROM session WHERE id = ?", sessionID).Scan(&projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("session %s not found", sessionID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query session %s: %w", sessionID, err)
+	}
+
+	return filepath.Join(filepath.Dir(s.path), "snapshot", projectID), nil
+}
+
+// scanSession reads a row of sessionColumns
+func scanSession(row interface{ Scan(dest ...any) error }) (*sessionRecord, error) {
+	var record sessionRecord
+	var parentID, shareURL sql.NullString
+
+	err := row.Scan(&record.ID, &record.ProjectID, &parentID, &record.Directory, &record.Title,
+		&record.Version, &shareURL, &record.Time.Created, &record.Time.Updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session row: %w", err)
+	}
+
+	if parentID.Valid {
+		record.ParentID = &parentID.String
+	}
+	if shareURL.Valid {
+		record.Share = &sessionShare{URL: shareURL.String}
+	}
+
+	return &record, nil
+}
+
+// info converts the record to SessionInfo, keeping the encoded record as Raw
+func (r *sessionRecord) info() (*SessionInfo, error) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session %s: %w", r.ID, err)
+	}
+
+	var info SessionInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", r.ID, err)
+	}
+	info.Raw = raw
+
+	return &info, nil
+}
+
+// recordJSON restores a message or part row to the shape of its file: the
+// data column with the ID columns added back, and, when the data has no
+// time, a time object from the row's creation time if created is set
+func recordJSON(data []byte, ids map[string]string, created int64) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+
+	for key, value := range ids {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = encoded
+	}
+
+	if _, ok := fields["time"]; !ok && created != 0 {
+		encoded, err := json.Marshal(map[string]int64{"created": created})
+		if err != nil {
+			return nil, err
+		}
+		fields["time"] = encoded
+	}
+
+	return json.Marshal(fields)
+}
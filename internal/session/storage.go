@@ -0,0 +1,171 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fantomc0der/opencode-session-export/internal/config"
+)
+
+// Layout identifies one of opencode's on-disk storage layouts
+type Layout string
+
+const (
+	// LayoutLegacy stores sessions under project/<name>/storage/session/...
+	LayoutLegacy Layout = "legacy"
+	// LayoutHash stores sessions under storage/session/<projectID>/ with
+	// messages and parts in top-level directories
+	LayoutHash Layout = "hash"
+	// LayoutSQLite stores sessions in the opencode.db database
+	LayoutSQLite Layout = "sqlite"
+)
+
+// Storage is a backend for one of opencode's storage layouts. Supporting a
+// new layout only takes a new Storage; Reader works the same on all of them.
+//
+// Every Storage is also either a FileStorage, for layouts that keep each
+// record in its own JSON file, or a RecordStorage, for databases.
+type Storage interface {
+	// Layout identifies the storage layout
+	Layout() Layout
+	// Sessions lists the stored sessions with the project each belongs to.
+	// Unless fresh is set, Info may predate the latest change to a session;
+	// IDs, paths and project directories are always current.
+	Sessions(fresh bool) ([]StoredSession, error)
+	// InfoPath returns the file holding a session's metadata: its info file,
+	// or the database file for a database. It returns an error if the
+	// session is not in this storage.
+	InfoPath(sessionID string) (string, error)
+	// SnapshotDir returns where opencode keeps the snapshot repository for
+	// the project a session belongs to; it need not exist
+	SnapshotDir(sessionID string) (string, error)
+}
+
+// FileStorage is a Storage keeping each session, message and part in its own
+// JSON file. Reader reads and decodes the files itself, so it can read them
+// concurrently and offer raw file access for pack and incremental watching.
+type FileStorage interface {
+	Storage
+	// MessageDir returns the directory holding a session's message files
+	MessageDir(sessionID string) string
+	// PartRoot returns the directory holding the per-message part
+	// directories of a session
+	PartRoot(sessionID string) string
+	// PartDir returns the directory holding a message's part files
+	PartDir(sessionID, messageID string) string
+}
+
+// RecordStorage is a Storage keeping sessions as database records, which it
+// decodes itself. Their Raw fields hold the records as JSON objects shaped
+// like the files of the file-based layouts.
+type RecordStorage interface {
+	Storage
+	// ReadInfo returns a session's metadata
+	ReadInfo(sessionID string) (*SessionInfo, error)
+	// ReadMessages returns a session's messages in any order
+	ReadMessages(sessionID string) ([]Message, error)
+	// ReadParts returns a message's parts in any order
+	ReadParts(sessionID, messageID string) ([]MessagePart, error)
+}
+
+// ErrNotFileStorage is returned for operations on the raw storage files of
+// a session kept in a database
+var ErrNotFileStorage = errors.New("session is stored in a database, not in files")
+
+// StoredSession is a session as listed by a Storage
+type StoredSession struct {
+	ID          string
+	Project     string // Name the project is listed under
+	ProjectPath string // Path the project is grouped by; empty if unknown
+	Directory   string // Directory the session was started in, when recorded
+	InfoPath    string
+	Info        SessionInfo
+}
+
+// ProjectStorage returns the storage holding the sessions of the project at
+// projectPath: opencode's database if there is one, then the project's legacy
+// directory if there is one, else the hash-based store if it exists or there
+// is no database. The database and the hash-based store are limited to
+// sessions started in that directory.
+//
+// Readers look sessions up in that order, so a session in both the database
+// and the files is read from the database, while sessions written to files
+// next to the database, as import and unpack do when given a layout, are
+// still found.
+func ProjectStorage(projectPath string) ([]Storage, error) {
+	dataDir, err := config.GetOpencodeDataDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opencode data directory: %w", err)
+	}
+
+	absProjectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project path: %w", err)
+	}
+
+	var stores []Storage
+
+	// opencode copies the JSON files into the database when it creates it
+	// and stops updating them, so the database is the current copy
+	dbPath := filepath.Join(dataDir, DatabaseFile)
+	if fileExists(dbPath) {
+		stores = append(stores, &sqliteStorage{path: dbPath, directory: absProjectPath})
+	}
+
+	storageDir, err := config.GetStorageDir(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage directory: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(storageDir, "session")); err == nil {
+		return append(stores, newLegacyStorage(storageDir)), nil
+	}
+
+	hashDir := filepath.Join(dataDir, "storage")
+	if _, err := os.Stat(filepath.Join(hashDir, "session")); err == nil || len(stores) == 0 {
+		stores = append(stores, &hashStorage{dir: hashDir, directory: absProjectPath})
+	}
+
+	return stores, nil
+}
+
+// AllStorage returns every storage found in opencode's data directory: the
+// database if it exists, one store per legacy project directory, then the
+// hash-based store if it exists. Readers look sessions up in that order, so
+// a session migrated into the database is read from there.
+func AllStorage() ([]Storage, error) {
+	dataDir, err := config.GetOpencodeDataDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opencode data directory: %w", err)
+	}
+
+	var stores []Storage
+
+	if dbPath := filepath.Join(dataDir, DatabaseFile); fileExists(dbPath) {
+		stores = append(stores, &sqliteStorage{path: dbPath})
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dataDir, "project"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read projects directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			stores = append(stores, newLegacyStorage(filepath.Join(dataDir, "project", entry.Name(), "storage")))
+		}
+	}
+
+	hashDir := filepath.Join(dataDir, "storage")
+	if _, err := os.Stat(filepath.Join(hashDir, "session")); err == nil {
+		stores = append(stores, &hashStorage{dir: hashDir})
+	}
+
+	return stores, nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ChildSessions returns the IDs of the sessions started from sessionID, such
+// as the subagent sessions of its task tool calls, oldest first
+func (r *Reader) ChildSessions(sessionID string) ([]string, error) {
+	children, err := r.childIndex()
+	if err != nil {
+		return nil, err
+	}
+	return children[sessionID], nil
+}
+
+// ReadSessionTree reads a session as ReadSession does, with its child
+// sessions, and theirs, read into Children
+func (r *Reader) ReadSessionTree(sessionID string) (*Session, error) {
+	children, err := r.childIndex()
+	if err != nil {
+		return nil, err
+	}
+	return r.readTree(sessionID, children, make(map[string]bool))
+}
+
+func (r *Reader) readTree(sessionID string, children map[string][]string, seen map[string]bool) (*Session, error) {
+	seen[sessionID] = true
+
+	sess, err := r.ReadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, childID := range children[sessionID] {
+		// Parent links never form a cycle in opencode's own data, but a
+		// hand-edited or imported session could make one
+		if seen[childID] {
+			continue
+		}
+		child, err := r.readTree(childID, children, seen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read child session %s: %w", childID, err)
+		}
+		sess.Children = append(sess.Children, child)
+	}
+
+	return sess, nil
+}
+
+// childIndex maps each session ID to the IDs of its child sessions, oldest
+// first. Parent links never change, so cached metadata is good enough.
+func (r *Reader) childIndex() (map[string][]string, error) {
+	defer loadCache().save()
+
+	var stored []StoredSession
+	seen := make(map[string]bool)
+	for _, store := range r.stores {
+		sessions, err := store.Sessions(false)
+		if err != nil {
+			return nil, err
+		}
+		for _, sess := range sessions {
+			if !seen[sess.ID] && sess.Info.ParentID != nil {
+				seen[sess.ID] = true
+				stored = append(stored, sess)
+			}
+		}
+	}
+
+	sort.SliceStable(stored, func(i, j int) bool {
+		return stored[i].Info.Time.Created < stored[j].Info.Time.Created
+	})
+
+	children := make(map[string][]string)
+	for _, sess := range stored {
+		parentID := *sess.Info.ParentID
+		children[parentID] = append(children[parentID], sess.ID)
+	}
+
+	return children, nil
+}
+
+// TaskSessionID returns the ID of the child session a task tool call ran
+// its subagent in, or "" if the part is not such a call
+func TaskSessionID(part *MessagePart) string {
+	if part.Type != "tool" {
+		return ""
+	}
+
+	var toolData ToolPartData
+	if part.Tool != nil && part.State != nil {
+		if err := json.Unmarshal(part.State, &toolData.State); err != nil {
+			return ""
+		}
+	} else if err := json.Unmarshal(part.Data, &toolData); err != nil {
+		return ""
+	}
+
+	var metadata struct {
+		SessionID string `json:"sessionId"`
+	}
+	if len(toolData.State.Metadata) == 0 || json.Unmarshal(toolData.State.Metadata, &metadata) != nil {
+		return ""
+	}
+	return metadata.SessionID
+}
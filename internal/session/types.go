@@ -13,6 +13,36 @@ type SessionInfo struct {
 	Version  string   `json:"version"`
 	Time     TimeInfo `json:"time"`
 	ShareURL *string  `json:"shareUrl,omitempty"`
+
+	// Raw holds the file contents this value was decoded from, so exports can
+	// carry fields this package does not model
+	Raw json.RawMessage `json:"-"`
+
+	// SnapshotDir is opencode's snapshot repository for the session's
+	// project, set when the Reader resolves snapshots and the project has one
+	SnapshotDir string `json:"-"`
+}
+
+// UnmarshalJSON decodes session info. The share URL is read from the flat
+// shareUrl field or from the share object newer opencode versions write, so
+// every decode of Raw, not just the first, finds it.
+func (s *SessionInfo) UnmarshalJSON(data []byte) error {
+	type plain SessionInfo // Without this method
+	var record struct {
+		plain
+		Share *struct {
+			URL string `json:"url"`
+		} `json:"share"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	*s = SessionInfo(record.plain)
+	if s.ShareURL == nil && record.Share != nil && record.Share.URL != "" {
+		s.ShareURL = &record.Share.URL
+	}
+	return nil
 }
 
 // TimeInfo represents the time information in session
@@ -31,7 +61,8 @@ func (s *SessionInfo) GetUpdatedAt() time.Time {
 	return time.UnixMilli(s.Time.Updated)
 }
 
-// Message represents a message in the session
+// Message represents a message in the session, normalized from whichever
+// schema it was stored in
 type Message struct {
 	ID        string    `json:"id"`
 	SessionID string    `json:"sessionID"`
@@ -39,12 +70,23 @@ type Message struct {
 	Time      *TimeInfo `json:"time,omitempty"`
 
 	// Assistant-specific fields
-	Model        *string  `json:"model,omitempty"`
-	Provider     *string  `json:"provider,omitempty"`
-	Cost         *float64 `json:"cost,omitempty"`
-	InputTokens  *int     `json:"inputTokens,omitempty"`
-	OutputTokens *int     `json:"outputTokens,omitempty"`
-	CompletedAt  *int64   `json:"completedAt,omitempty"`
+	Model        *string       `json:"model,omitempty"`
+	Provider     *string       `json:"provider,omitempty"`
+	Cost         *float64      `json:"cost,omitempty"`
+	InputTokens  *int          `json:"inputTokens,omitempty"`
+	OutputTokens *int          `json:"outputTokens,omitempty"`
+	Tokens       *StepTokens   `json:"tokens,omitempty"` // With reasoning and cache counts, when recorded
+	CompletedAt  *int64        `json:"completedAt,omitempty"`
+	Path         *MessagePath  `json:"path,omitempty"`
+	Mode         *string       `json:"mode,omitempty"` // Agent mode, e.g. "build" or "plan"
+	System       []string      `json:"system,omitempty"`
+	Error        *MessageError `json:"error,omitempty"`
+
+	// Schema is the shape the message was stored in
+	Schema MessageSchema `json:"-"`
+
+	// Raw holds the file contents this message was decoded from
+	Raw json.RawMessage `json:"-"`
 }
 
 // GetCreatedAt returns the creation time as a time.Time
@@ -67,6 +109,9 @@ type MessagePart struct {
 	State     json.RawMessage `json:"state,omitempty"`  // For tool parts
 	Data      json.RawMessage `json:"data,omitempty"`   // For other parts
 	Time      *PartTimeData   `json:"time,omitempty"`   // Time can be object or int64
+
+	// Raw holds the file contents this part was decoded from
+	Raw json.RawMessage `json:"-"`
 }
 
 // GetCreatedAt returns the creation time as a time.Time
@@ -124,4 +169,8 @@ type Session struct {
 	Info     SessionInfo   `json:"info"`
 	Messages []Message     `json:"messages"`
 	Parts    []MessagePart `json:"parts"`
+
+	// Children holds the sessions started from this one, such as subagent
+	// sessions, when read with Reader.ReadSessionTree
+	Children []*Session `json:"children,omitempty"`
 }
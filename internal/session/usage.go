@@ -0,0 +1,127 @@
+package session
+
+import "encoding/json"
+
+// StepTokens counts the tokens of one step of an assistant message, or of
+// several steps added together
+type StepTokens struct {
+	Input     int         `json:"input"`
+	Output    int         `json:"output"`
+	Reasoning int         `json:"reasoning"`
+	Cache     CacheTokens `json:"cache"`
+}
+
+// CacheTokens counts the tokens read from and written to the provider's
+// prompt cache
+type CacheTokens struct {
+	Read  int `json:"read"`
+	Write int `json:"write"`
+}
+
+// Add adds other's counts to t
+func (t *StepTokens) Add(other StepTokens) {
+	t.Input += other.Input
+	t.Output += other.Output
+	t.Reasoning += other.Reasoning
+	t.Cache.Read += other.Cache.Read
+	t.Cache.Write += other.Cache.Write
+}
+
+// IsZero reports whether no tokens were counted
+func (t StepTokens) IsZero() bool {
+	return t == StepTokens{}
+}
+
+// StepStart is a step-start part. opencode writes one when a step of an
+// assistant message begins: one model call, which may end in tool calls.
+type StepStart struct {
+	Snapshot string `json:"snapshot,omitempty"` // Snapshot of the worktree as the step began
+}
+
+// StepFinish is a step-finish part, recording what a step of an assistant
+// message used and why it ended
+type StepFinish struct {
+	Reason   string     `json:"reason,omitempty"`   // e.g. "stop" or "tool-calls"
+	Snapshot string     `json:"snapshot,omitempty"` // Snapshot of the worktree as the step finished
+	Cost     float64    `json:"cost"`
+	Tokens   StepTokens `json:"tokens"`
+}
+
+// ParseStepStart decodes a step-start part. It returns nil for other parts.
+func ParseStepStart(part *MessagePart) *StepStart {
+	if part.Type != "step-start" {
+		return nil
+	}
+	var step StepStart
+	if json.Unmarshal(part.Raw, &step) != nil {
+		return nil
+	}
+	return &step
+}
+
+// ParseStepFinish decodes a step-finish part. It returns nil for other parts.
+func ParseStepFinish(part *MessagePart) *StepFinish {
+	if part.Type != "step-finish" {
+		return nil
+	}
+	var step StepFinish
+	if json.Unmarshal(part.Raw, &step) != nil {
+		return nil
+	}
+	return &step
+}
+
+// Usage totals the tokens and cost of steps
+type Usage struct {
+	Steps  int
+	Cost   float64
+	Tokens StepTokens
+}
+
+// AddStep counts a finished step toward the totals
+func (u *Usage) AddStep(step *StepFinish) {
+	u.Steps++
+	u.Cost += step.Cost
+	u.Tokens.Add(step.Tokens)
+}
+
+// Add adds other's totals to u
+func (u *Usage) Add(other Usage) {
+	u.Steps += other.Steps
+	u.Cost += other.Cost
+	u.Tokens.Add(other.Tokens)
+}
+
+// IsZero reports whether nothing was counted
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// MessageUsage totals the steps of one assistant message. A message without
+// step-finish parts, as older opencode versions wrote them, is counted by
+// its own cost and tokens, with no steps.
+func MessageUsage(message *Message, parts []MessagePart) Usage {
+	var usage Usage
+	for i := range parts {
+		if step := ParseStepFinish(&parts[i]); step != nil {
+			usage.AddStep(step)
+		}
+	}
+	if usage.Steps > 0 || message == nil {
+		return usage
+	}
+
+	if message.Cost != nil {
+		usage.Cost = *message.Cost
+	}
+	usage.Tokens = messageTokens(message)
+	return usage
+}
+
+// messageTokens returns the tokens a message records for itself
+func messageTokens(message *Message) StepTokens {
+	if message.Tokens == nil {
+		return StepTokens{}
+	}
+	return *message.Tokens
+}
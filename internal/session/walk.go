@@ -0,0 +1,46 @@
+package session
+
+import "fmt"
+
+// WalkFunc is called for each message of a session, in order, with the
+// message's parts in order. Returning an error stops the walk.
+type WalkFunc func(message *Message, parts []MessagePart) error
+
+// WalkMessages calls fn for each message of a session in the order
+// ReadSession returns them. Parts are read one message at a time, so only the
+// current message's parts are held in memory however large the session is.
+func (r *Reader) WalkMessages(sessionID string, fn WalkFunc) error {
+	messages, err := r.ReadMessages(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	for i := range messages {
+		parts, err := r.ReadMessageParts(sessionID, messages[i].ID)
+		if err != nil {
+			parts = nil // Skip messages with corrupted parts, as ReadSession does
+		}
+		if err := fn(&messages[i], parts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Walk calls fn for each message of a loaded session with its parts, in the
+// same way Reader.WalkMessages does for a session in storage
+func (s *Session) Walk(fn WalkFunc) error {
+	partsByMessage := make(map[string][]MessagePart)
+	for _, part := range s.Parts {
+		partsByMessage[part.MessageID] = append(partsByMessage[part.MessageID], part)
+	}
+
+	for i := range s.Messages {
+		if err := fn(&s.Messages[i], partsByMessage[s.Messages[i].ID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
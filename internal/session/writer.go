@@ -0,0 +1,207 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fantomc0der/opencode-session-export/internal/config"
+)
+
+// idPattern matches the characters opencode uses in session, message and part IDs
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// IsValidID reports whether id is safe to use as a storage file name. IDs
+// from exports must be checked before writing, since a crafted ID such as
+// "../x" would otherwise escape the storage directory.
+func IsValidID(id string) bool {
+	return idPattern.MatchString(id)
+}
+
+// Writer writes raw session files into opencode's storage so that Reader,
+// and opencode itself, can load them
+type Writer struct {
+	storage writableStorage
+}
+
+// writableStorage is a FileStorage that new sessions can be written into. It
+// places them under the project the storage was opened for.
+type writableStorage interface {
+	FileStorage
+	// root returns the storage directory
+	root() string
+	// newInfoPath returns where a session's info file is written
+	newInfoPath(sessionID string) string
+	// infoPaths returns every stored info file of a session
+	infoPaths(sessionID string) ([]string, error)
+	// adoptInfo rewrites the fields of a session's info that name the project
+	// it belongs to, so it is listed under the storage's project. It reports
+	// whether it changed anything.
+	adoptInfo(fields map[string]interface{}) bool
+	// projectSnapshotDir returns where opencode keeps the snapshot
+	// repository of the storage's project
+	projectSnapshotDir() string
+}
+
+// NewWriter creates a writer for the project at projectPath. An empty layout
+// picks the one NewReader would read from for the same project.
+func NewWriter(projectPath string, layout Layout) (*Writer, error) {
+	absProjectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project path: %w", err)
+	}
+
+	legacyStorageDir, err := config.GetStorageDir(absProjectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage directory: %w", err)
+	}
+
+	if layout == "" {
+		// Files written next to a database are not read by opencode, so
+		// only write them when asked to explicitly
+		if dataDir, err := config.GetOpencodeDataDir(); err == nil && fileExists(filepath.Join(dataDir, DatabaseFile)) {
+			return nil, fmt.Errorf("opencode keeps sessions in %s, which cannot be written to; pass a layout to write JSON files anyway", DatabaseFile)
+		}
+
+		layout = LayoutHash
+		if _, err := os.Stat(filepath.Join(legacyStorageDir, "session")); err == nil {
+			layout = LayoutLegacy
+		}
+	}
+
+	switch layout {
+	case LayoutLegacy:
+		return &Writer{storage: newLegacyStorage(legacyStorageDir)}, nil
+	case LayoutHash:
+		dataDir, err := config.GetOpencodeDataDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get opencode data directory: %w", err)
+		}
+		return &Writer{storage: &hashStorage{
+			dir:       filepath.Join(dataDir, "storage"),
+			directory: absProjectPath,
+			projectID: config.GetProjectID(absProjectPath),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage layout %q (use legacy or hash)", layout)
+	}
+}
+
+// Layout returns the storage layout the writer targets
+func (w *Writer) Layout() Layout {
+	return w.storage.Layout()
+}
+
+// StorageDir returns the storage root the writer targets
+func (w *Writer) StorageDir() string {
+	return w.storage.root()
+}
+
+// SnapshotDir returns where opencode keeps the snapshot repository for the
+// target project
+func (w *Writer) SnapshotDir() string {
+	return w.storage.projectSnapshotDir()
+}
+
+// SessionExists reports whether a session with the given ID is already stored
+func (w *Writer) SessionExists(sessionID string) bool {
+	if _, err := os.Stat(w.storage.newInfoPath(sessionID)); err == nil {
+		return true
+	}
+	if _, err := os.Stat(w.storage.MessageDir(sessionID)); err == nil {
+		return true
+	}
+	return false
+}
+
+// MessageExists reports whether parts for the given message are already
+// stored for a session other than sessionID. In the hash layout part
+// directories are shared by all sessions, so this catches ID clashes that
+// removing or overwriting sessionID would not clear.
+func (w *Writer) MessageExists(sessionID, messageID string) bool {
+	if _, err := os.Stat(w.storage.PartDir(sessionID, messageID)); err != nil {
+		return false
+	}
+	// Parts of sessionID's own message are replaced along with the session
+	_, err := os.Stat(filepath.Join(w.storage.MessageDir(sessionID), messageID+".json"))
+	return err != nil
+}
+
+// RemoveSession deletes a stored session along with its messages and parts
+func (w *Writer) RemoveSession(sessionID string) error {
+	messageDir := w.storage.MessageDir(sessionID)
+
+	entries, err := os.ReadDir(messageDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read message directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		messageID := strings.TrimSuffix(entry.Name(), ".json")
+		if err := os.RemoveAll(w.storage.PartDir(sessionID, messageID)); err != nil {
+			return fmt.Errorf("failed to remove parts of message %s: %w", messageID, err)
+		}
+	}
+
+	if err := os.RemoveAll(messageDir); err != nil {
+		return fmt.Errorf("failed to remove message directory: %w", err)
+	}
+
+	infoPaths, err := w.storage.infoPaths(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to find session info: %w", err)
+	}
+	for _, infoPath := range infoPaths {
+		if err := os.Remove(infoPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove session info: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// WriteSessionInfo stores the session metadata file. Where the layout records
+// the project in the file, as the hash layout does, those fields are
+// rewritten so the session is listed under the target project rather than
+// the machine it was exported from.
+func (w *Writer) WriteSessionInfo(sessionID string, data json.RawMessage) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to parse session info: %w", err)
+	}
+	if w.storage.adoptInfo(fields) {
+		rewritten, err := json.MarshalIndent(fields, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode session info: %w", err)
+		}
+		data = rewritten
+	}
+
+	return writeJSONFile(w.storage.newInfoPath(sessionID), data)
+}
+
+// WriteMessage stores a message file
+func (w *Writer) WriteMessage(sessionID, messageID string, data json.RawMessage) error {
+	return writeJSONFile(filepath.Join(w.storage.MessageDir(sessionID), messageID+".json"), data)
+}
+
+// WritePart stores a message part file
+func (w *Writer) WritePart(sessionID, messageID, partID string, data json.RawMessage) error {
+	return writeJSONFile(filepath.Join(w.storage.PartDir(sessionID, messageID), partID+".json"), data)
+}
+
+func writeJSONFile(path string, data json.RawMessage) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Package snapshot reads opencode's snapshot repositories. opencode records
+// the state of a project's worktree before and after each step of an
+// assistant message as trees in a git repository of its own, kept in its data
+// directory, and names those trees in step and patch parts. Diffing two of
+// them shows exactly what the step changed.
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Repo is a snapshot repository
+type Repo struct {
+	dir string
+}
+
+// Open returns the snapshot repository in dir, or nil if dir is empty
+func Open(dir string) *Repo {
+	if dir == "" {
+		return nil
+	}
+	return &Repo{dir: dir}
+}
+
+// Diff returns the unified diff from snapshot from to snapshot to
+func (r *Repo) Diff(from, to string) (string, error) {
+	// Hashes come from session files, so never let one pass for an option
+	if !isHash(from) || !isHash(to) {
+		return "", fmt.Errorf("invalid snapshot hash")
+	}
+
+	// The repository's attributes could name textconv filters or external
+	// diff drivers that run commands, so ignore them
+	cmd := exec.Command("git", "-c", "core.attributesFile="+os.DevNull, "--git-dir", r.dir,
+		"diff", "--no-color", "--no-ext-diff", "--no-textconv", "--no-renames", from, to)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("failed to diff snapshots %s and %s: %s", Short(from), Short(to), message)
+		}
+		return "", fmt.Errorf("failed to diff snapshots %s and %s: %w", Short(from), Short(to), err)
+	}
+
+	return string(output), nil
+}
+
+// Short abbreviates a snapshot hash for display
+func Short(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+// isHash reports whether s is a hexadecimal object name
+func isHash(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes the report as one flat table so it can be pivoted in a
+// spreadsheet. The section column says which breakdown a row belongs to.
+func (r *Report) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"section", "key", "project", "messages", "calls", "errors", "cost", "input_tokens", "output_tokens",
+		"reasoning_tokens", "cache_read_tokens", "cache_write_tokens"})
+
+	row := func(section, key, project string, totals Totals) {
+		out.Write([]string{
+			section,
+			key,
+			project,
+			strconv.Itoa(totals.Messages),
+			"",
+			"",
+			strconv.FormatFloat(totals.Cost, 'f', 6, 64),
+			strconv.FormatInt(totals.InputTokens, 10),
+			strconv.FormatInt(totals.OutputTokens, 10),
+			strconv.FormatInt(totals.ReasoningTokens, 10),
+			strconv.FormatInt(totals.CacheReadTokens, 10),
+			strconv.FormatInt(totals.CacheWriteTokens, 10),
+		})
+	}
+
+	row("total", "", "", r.Total)
+	for _, group := range r.ByModel {
+		row("model", group.Key, "", group.Totals)
+	}
+	for _, group := range r.ByProvider {
+		row("provider", group.Key, "", group.Totals)
+	}
+	for _, group := range r.ByDay {
+		row("day", group.Key, "", group.Totals)
+	}
+	for _, group := range r.ByWeek {
+		row("week", group.Key, "", group.Totals)
+	}
+	for _, sess := range r.TopSessions {
+		row("session", sess.SessionID, sess.ProjectName, sess.Totals)
+	}
+	for _, tool := range r.Tools {
+		out.Write([]string{"tool", tool.Tool, "", "", strconv.Itoa(tool.Calls), strconv.Itoa(tool.Errors), "", "", "", "", "", ""})
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// WriteTable writes the report as aligned plain-text tables
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Sessions: %d\n", r.Sessions)
+	if r.Since != nil || r.Until != nil {
+		from, to := "beginning", "now"
+		if r.Since != nil {
+			from = r.Since.Format("2006-01-02")
+		}
+		if r.Until != nil {
+			to = r.Until.AddDate(0, 0, -1).Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "Range:    %s to %s\n", from, to)
+	}
+	fmt.Fprintf(w, "Cost:     $%.4f\n", r.Total.Cost)
+	fmt.Fprintf(w, "Tokens:   %s\n", formatTokens(r.Total))
+	fmt.Fprintf(w, "Messages: %d assistant message(s)\n", r.Total.Messages)
+
+	writeGroups(w, tw, "By model", "MODEL", r.ByModel)
+	writeGroups(w, tw, "By provider", "PROVIDER", r.ByProvider)
+	writeGroups(w, tw, "By day", "DAY", r.ByDay)
+	writeGroups(w, tw, "By week", "WEEK", r.ByWeek)
+
+	if len(r.TopSessions) > 0 {
+		fmt.Fprintf(w, "\nMost expensive sessions\n")
+		fmt.Fprintln(tw, "SESSION\tPROJECT\tTITLE\tMESSAGES\tCOST\tTOKENS IN\tTOKENS OUT")
+		for _, sess := range r.TopSessions {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t$%.4f\t%d\t%d\n",
+				sess.SessionID, sess.ProjectName, truncate(sess.Title, 40),
+				sess.Messages, sess.Cost, sess.InputTokens, sess.OutputTokens)
+		}
+		tw.Flush()
+	}
+
+	if len(r.Tools) > 0 {
+		fmt.Fprintf(w, "\nTool usage\n")
+		fmt.Fprintln(tw, "TOOL\tCALLS\tERRORS")
+		for _, tool := range r.Tools {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", tool.Tool, tool.Calls, tool.Errors)
+		}
+		tw.Flush()
+	}
+
+	return nil
+}
+
+func writeGroups(w io.Writer, tw *tabwriter.Writer, title, column string, groups []Group) {
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s\n", title)
+	fmt.Fprintf(tw, "%s\tMESSAGES\tCOST\tTOKENS IN\tTOKENS OUT\n", column)
+	for _, group := range groups {
+		fmt.Fprintf(tw, "%s\t%d\t$%.4f\t%d\t%d\n",
+			group.Key, group.Messages, group.Cost, group.InputTokens, group.OutputTokens)
+	}
+	tw.Flush()
+}
+
+// formatTokens describes token counts in a line, leaving out the zero
+// reasoning and cache counts as the export totals do
+func formatTokens(t Totals) string {
+	line := fmt.Sprintf("%d in, %d out", t.InputTokens, t.OutputTokens)
+	if t.ReasoningTokens > 0 {
+		line += fmt.Sprintf(", %d reasoning", t.ReasoningTokens)
+	}
+	if t.CacheReadTokens > 0 {
+		line += fmt.Sprintf(", %d cache read", t.CacheReadTokens)
+	}
+	if t.CacheWriteTokens > 0 {
+		line += fmt.Sprintf(", %d cache write", t.CacheWriteTokens)
+	}
+	return line
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
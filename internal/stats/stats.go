@@ -0,0 +1,278 @@
+// Package stats aggregates cost, token and tool usage across sessions
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Totals are the summed usage of a group of assistant messages, counted from
+// their steps as the export totals are
+type Totals struct {
+	Messages         int     `json:"messages"`
+	Cost             float64 `json:"cost"`
+	InputTokens      int64   `json:"inputTokens"`
+	OutputTokens     int64   `json:"outputTokens"`
+	ReasoningTokens  int64   `json:"reasoningTokens"`
+	CacheReadTokens  int64   `json:"cacheReadTokens"`
+	CacheWriteTokens int64   `json:"cacheWriteTokens"`
+}
+
+// Group is the usage attributed to one model, provider, day or week
+type Group struct {
+	Key string `json:"key"`
+	Totals
+}
+
+// SessionTotals is the usage of a single session
+type SessionTotals struct {
+	SessionID   string `json:"sessionID"`
+	Title       string `json:"title"`
+	ProjectName string `json:"project"`
+	Totals
+}
+
+// ToolCount is how often a tool was called and how many calls failed
+type ToolCount struct {
+	Tool   string `json:"tool"`
+	Calls  int    `json:"calls"`
+	Errors int    `json:"errors"`
+}
+
+// Report is the aggregated result
+type Report struct {
+	Since       *time.Time      `json:"since,omitempty"`
+	Until       *time.Time      `json:"until,omitempty"`
+	Sessions    int             `json:"sessions"`
+	Total       Totals          `json:"total"`
+	ByModel     []Group         `json:"byModel"`
+	ByProvider  []Group         `json:"byProvider"`
+	ByDay       []Group         `json:"byDay"`
+	ByWeek      []Group         `json:"byWeek"`
+	TopSessions []SessionTotals `json:"topSessions"`
+	Tools       []ToolCount     `json:"tools"`
+}
+
+// Aggregator accumulates sessions into a report. Only messages created in
+// [Since, Until) count; a zero bound is open.
+type Aggregator struct {
+	Since time.Time
+	Until time.Time
+
+	sessions   []SessionTotals
+	total      Totals
+	byModel    map[string]*Totals
+	byProvider map[string]*Totals
+	byDay      map[string]*Totals
+	byWeek     map[string]*Totals
+	tools      map[string]*ToolCount
+}
+
+// NewAggregator creates an aggregator for the given date range
+func NewAggregator(since, until time.Time) *Aggregator {
+	return &Aggregator{
+		Since:      since,
+		Until:      until,
+		byModel:    make(map[string]*Totals),
+		byProvider: make(map[string]*Totals),
+		byDay:      make(map[string]*Totals),
+		byWeek:     make(map[string]*Totals),
+		tools:      make(map[string]*ToolCount),
+	}
+}
+
+// Add accumulates one session. Sessions with no messages in range are ignored.
+func (a *Aggregator) Add(sess *session.Session, projectName string) {
+	sessionTotals := SessionTotals{
+		SessionID:   sess.Info.ID,
+		Title:       sess.Info.Title,
+		ProjectName: projectName,
+	}
+
+	partsByMessage := make(map[string][]session.MessagePart)
+	for _, part := range sess.Parts {
+		partsByMessage[part.MessageID] = append(partsByMessage[part.MessageID], part)
+	}
+
+	inRange := make(map[string]bool, len(sess.Messages))
+	for _, msg := range sess.Messages {
+		created := msg.GetCreatedAt()
+		if !a.Since.IsZero() && created.Before(a.Since) {
+			continue
+		}
+		if !a.Until.IsZero() && !created.Before(a.Until) {
+			continue
+		}
+		inRange[msg.ID] = true
+
+		if msg.Role != "assistant" {
+			continue
+		}
+
+		usage := messageTotals(&msg, partsByMessage[msg.ID])
+		sessionTotals.add(usage)
+		a.total.add(usage)
+
+		provider := "unknown"
+		if msg.Provider != nil && *msg.Provider != "" {
+			provider = *msg.Provider
+		}
+		model := "unknown"
+		if msg.Model != nil && *msg.Model != "" {
+			model = *msg.Model
+		}
+
+		addTo(a.byModel, provider+"/"+model, usage)
+		addTo(a.byProvider, provider, usage)
+		if !created.IsZero() {
+			addTo(a.byDay, created.Format("2006-01-02"), usage)
+			year, week := created.ISOWeek()
+			addTo(a.byWeek, fmt.Sprintf("%04d-W%02d", year, week), usage)
+		}
+	}
+
+	if len(inRange) == 0 {
+		return
+	}
+
+	for _, part := range sess.Parts {
+		if part.Type != "tool" || !inRange[part.MessageID] {
+			continue
+		}
+		tool, status := toolCall(part)
+		if tool == "" {
+			continue
+		}
+
+		count, ok := a.tools[tool]
+		if !ok {
+			count = &ToolCount{Tool: tool}
+			a.tools[tool] = count
+		}
+		count.Calls++
+		if status == "error" {
+			count.Errors++
+		}
+	}
+
+	a.sessions = append(a.sessions, sessionTotals)
+}
+
+// Report returns the aggregated figures with at most top sessions listed,
+// most expensive first. A top of zero or less lists every session.
+func (a *Aggregator) Report(top int) *Report {
+	report := &Report{
+		Sessions:   len(a.sessions),
+		Total:      a.total,
+		ByModel:    sortedByCost(a.byModel),
+		ByProvider: sortedByCost(a.byProvider),
+		ByDay:      sortedByKey(a.byDay),
+		ByWeek:     sortedByKey(a.byWeek),
+	}
+	if !a.Since.IsZero() {
+		report.Since = &a.Since
+	}
+	if !a.Until.IsZero() {
+		report.Until = &a.Until
+	}
+
+	sessions := append([]SessionTotals(nil), a.sessions...)
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Cost > sessions[j].Cost
+	})
+	if top > 0 && len(sessions) > top {
+		sessions = sessions[:top]
+	}
+	report.TopSessions = sessions
+
+	for _, count := range a.tools {
+		report.Tools = append(report.Tools, *count)
+	}
+	sort.Slice(report.Tools, func(i, j int) bool {
+		if report.Tools[i].Calls != report.Tools[j].Calls {
+			return report.Tools[i].Calls > report.Tools[j].Calls
+		}
+		return report.Tools[i].Tool < report.Tools[j].Tool
+	})
+
+	return report
+}
+
+func messageTotals(msg *session.Message, parts []session.MessagePart) Totals {
+	usage := session.MessageUsage(msg, parts)
+	return Totals{
+		Messages:         1,
+		Cost:             usage.Cost,
+		InputTokens:      int64(usage.Tokens.Input),
+		OutputTokens:     int64(usage.Tokens.Output),
+		ReasoningTokens:  int64(usage.Tokens.Reasoning),
+		CacheReadTokens:  int64(usage.Tokens.Cache.Read),
+		CacheWriteTokens: int64(usage.Tokens.Cache.Write),
+	}
+}
+
+// toolCall returns the tool name and status of a tool part in either the
+// direct or the data-wrapped representation
+func toolCall(part session.MessagePart) (string, string) {
+	if part.Tool != nil && part.State != nil {
+		var state session.ToolStateData
+		json.Unmarshal(part.State, &state)
+		return *part.Tool, state.Status
+	}
+
+	var toolData session.ToolPartData
+	if err := json.Unmarshal(part.Data, &toolData); err != nil {
+		return "", ""
+	}
+	return toolData.Tool, toolData.State.Status
+}
+
+func (t *Totals) add(other Totals) {
+	t.Messages += other.Messages
+	t.Cost += other.Cost
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.ReasoningTokens += other.ReasoningTokens
+	t.CacheReadTokens += other.CacheReadTokens
+	t.CacheWriteTokens += other.CacheWriteTokens
+}
+
+func addTo(groups map[string]*Totals, key string, usage Totals) {
+	totals, ok := groups[key]
+	if !ok {
+		totals = &Totals{}
+		groups[key] = totals
+	}
+	totals.add(usage)
+}
+
+func sortedByCost(groups map[string]*Totals) []Group {
+	result := toGroups(groups)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func sortedByKey(groups map[string]*Totals) []Group {
+	result := toGroups(groups)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func toGroups(groups map[string]*Totals) []Group {
+	result := make([]Group, 0, len(groups))
+	for key, totals := range groups {
+		result = append(result, Group{Key: key, Totals: *totals})
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+func TestAggregatorCountsSteps(t *testing.T) {
+	cost := 0.5
+	tokens := &session.StepTokens{Input: 100, Output: 200}
+	sess := &session.Session{
+		Info: session.SessionInfo{ID: "ses_a", Title: "Steps"},
+		Messages: []session.Message{
+			// Counted from its step-finish parts, not its own figures
+			{ID: "msg_a", SessionID: "ses_a", Role: "assistant", Cost: &cost, InputTokens: &tokens.Input, OutputTokens: &tokens.Output, Tokens: tokens},
+			// Without steps, its own figures count
+			{ID: "msg_b", SessionID: "ses_a", Role: "assistant", Cost: &cost, InputTokens: &tokens.Input, OutputTokens: &tokens.Output, Tokens: tokens},
+		},
+		Parts: []session.MessagePart{
+			stepFinish("prt_1", "msg_a", `{"cost":0.1,"tokens":{"input":10,"output":20,"reasoning":5,"cache":{"read":30,"write":1}}}`),
+			stepFinish("prt_2", "msg_a", `{"cost":0.2,"tokens":{"input":1,"output":2,"reasoning":0,"cache":{"read":3,"write":0}}}`),
+		},
+	}
+
+	aggregator := NewAggregator(time.Time{}, time.Time{})
+	aggregator.Add(sess, "proj")
+	got := aggregator.Report(0).Total
+
+	want := Totals{
+		Messages:         2,
+		Cost:             0.1 + 0.2 + 0.5,
+		InputTokens:      111,
+		OutputTokens:     222,
+		ReasoningTokens:  5,
+		CacheReadTokens:  33,
+		CacheWriteTokens: 1,
+	}
+	if got != want {
+		t.Errorf("Total = %+v, want %+v", got, want)
+	}
+}
+
+func stepFinish(id, messageID, fields string) session.MessagePart {
+	var raw map[string]any
+	json.Unmarshal([]byte(fields), &raw)
+	raw["id"] = id
+	raw["messageID"] = messageID
+	raw["type"] = "step-finish"
+	data, _ := json.Marshal(raw)
+	return session.MessagePart{ID: id, MessageID: messageID, Type: "step-finish", Raw: data}
+}
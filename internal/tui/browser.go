@@ -0,0 +1,408 @@
+// Package tui implements the interactive session browser. It draws directly
+// with ANSI escape sequences on a raw-mode terminal and knows nothing about
+// sessions beyond the items and callbacks it is given.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Item is one row in the browser list
+type Item struct {
+	ID     string
+	Title  string
+	Detail string // Secondary text shown dimmed after the title, e.g. project and date
+}
+
+// Options configures a browser run
+type Options struct {
+	Items []Item
+	// Preview returns the text shown in the preview pane for an item.
+	// Results are cached for the lifetime of the browser.
+	Preview func(item Item) (string, error)
+	// Export writes the given items and returns a status line to display
+	Export func(items []Item) (string, error)
+}
+
+type focus int
+
+const (
+	focusList focus = iota
+	focusPreview
+)
+
+const helpLine = "↑/↓ move  tab pane  / filter  space select  a all  e export  q quit"
+
+// browser holds the UI state between keypresses
+type browser struct {
+	opts Options
+
+	visible   []int // Indices into opts.Items that match the filter, in display order
+	cursor    int   // Position within visible
+	offset    int   // First visible row drawn in the list pane
+	selected  map[string]bool
+	filter    string
+	filtering bool
+	focus     focus
+
+	previewScroll int
+	previewCache  map[string]string
+	status        string
+}
+
+// Run shows the browser until the user quits. It needs stdin to be a terminal.
+func Run(opts Options) error {
+	if len(opts.Items) == 0 {
+		return fmt.Errorf("no sessions to browse")
+	}
+
+	t, err := openTerminal(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	b := &browser{
+		opts:         opts,
+		selected:     make(map[string]bool),
+		previewCache: make(map[string]string),
+	}
+	b.applyFilter()
+
+	for {
+		width, height := t.size()
+		b.draw(t, width, height)
+
+		k, err := t.readKey()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read key: %w", err)
+		}
+
+		if quit := b.handleKey(k, height); quit {
+			return nil
+		}
+	}
+}
+
+// handleKey updates state for one keypress and reports whether to quit
+func (b *browser) handleKey(k key, height int) bool {
+	page := max(bodyHeight(height)-1, 1)
+	b.status = ""
+
+	if k.kind == keyCtrlC {
+		return true
+	}
+
+	if b.filtering {
+		switch k.kind {
+		case keyRune:
+			b.filter += string(k.r)
+			b.applyFilter()
+			return false
+		case keyBackspace:
+			if runes := []rune(b.filter); len(runes) > 0 {
+				b.filter = string(runes[:len(runes)-1])
+				b.applyFilter()
+			}
+			return false
+		case keyEnter:
+			b.filtering = false
+			return false
+		case keyEscape:
+			b.filtering = false
+			b.filter = ""
+			b.applyFilter()
+			return false
+		}
+	}
+
+	switch k.kind {
+	case keyUp:
+		b.move(-1)
+	case keyDown:
+		b.move(1)
+	case keyPageUp:
+		b.scrollPreview(-page)
+	case keyPageDown:
+		b.scrollPreview(page)
+	case keyHome:
+		b.moveTo(0)
+	case keyEnd:
+		b.moveTo(len(b.visible) - 1)
+	case keyTab:
+		if b.focus == focusList {
+			b.focus = focusPreview
+		} else {
+			b.focus = focusList
+		}
+	case keyEnter:
+		b.export()
+	case keyEscape:
+		if b.filter != "" {
+			b.filter = ""
+			b.applyFilter()
+		}
+	case keyRune:
+		switch k.r {
+		case 'q':
+			return true
+		case 'k':
+			b.move(-1)
+		case 'j':
+			b.move(1)
+		case 'g':
+			b.moveTo(0)
+		case 'G':
+			b.moveTo(len(b.visible) - 1)
+		case '/':
+			b.filtering = true
+		case ' ':
+			b.toggleCurrent()
+		case 'a':
+			b.toggleAll()
+		case 'e':
+			b.export()
+		}
+	}
+
+	return false
+}
+
+// move steps the cursor in the list, or scrolls the preview when it has focus
+func (b *browser) move(delta int) {
+	if b.focus == focusPreview {
+		b.scrollPreview(delta)
+		return
+	}
+	b.moveTo(b.cursor + delta)
+}
+
+func (b *browser) moveTo(position int) {
+	if len(b.visible) == 0 {
+		b.cursor = 0
+		return
+	}
+	position = max(0, min(position, len(b.visible)-1))
+	if position != b.cursor {
+		b.cursor = position
+		b.previewScroll = 0
+	}
+}
+
+func (b *browser) scrollPreview(delta int) {
+	b.previewScroll = max(b.previewScroll+delta, 0)
+}
+
+// current returns the highlighted item, if any
+func (b *browser) current() (Item, bool) {
+	if len(b.visible) == 0 {
+		return Item{}, false
+	}
+	return b.opts.Items[b.visible[b.cursor]], true
+}
+
+func (b *browser) toggleCurrent() {
+	item, ok := b.current()
+	if !ok {
+		return
+	}
+	if b.selected[item.ID] {
+		delete(b.selected, item.ID)
+	} else {
+		b.selected[item.ID] = true
+	}
+	b.moveTo(b.cursor + 1)
+}
+
+// toggleAll selects every visible item, or clears them if all are selected
+func (b *browser) toggleAll() {
+	allSelected := true
+	for _, index := range b.visible {
+		if !b.selected[b.opts.Items[index].ID] {
+			allSelected = false
+			break
+		}
+	}
+
+	for _, index := range b.visible {
+		id := b.opts.Items[index].ID
+		if allSelected {
+			delete(b.selected, id)
+		} else {
+			b.selected[id] = true
+		}
+	}
+}
+
+// export hands the selected items, or the highlighted one if nothing is
+// selected, to the Export callback
+func (b *browser) export() {
+	var items []Item
+	for _, item := range b.opts.Items {
+		if b.selected[item.ID] {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		item, ok := b.current()
+		if !ok {
+			return
+		}
+		items = []Item{item}
+	}
+
+	status, err := b.opts.Export(items)
+	if err != nil {
+		b.status = "Export failed: " + err.Error()
+		return
+	}
+	b.status = status
+	b.selected = make(map[string]bool)
+}
+
+// applyFilter recomputes the visible rows, ranking fuzzy matches best first.
+// Without a filter items keep their original order.
+func (b *browser) applyFilter() {
+	type match struct {
+		index int
+		score int
+	}
+
+	var matches []match
+	for i, item := range b.opts.Items {
+		if score, ok := fuzzyScore(b.filter, item.Title); ok {
+			matches = append(matches, match{index: i, score: score})
+		}
+	}
+
+	if b.filter != "" {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].score > matches[j].score
+		})
+	}
+
+	b.visible = b.visible[:0]
+	for _, m := range matches {
+		b.visible = append(b.visible, m.index)
+	}
+	b.cursor = 0
+	b.offset = 0
+	b.previewScroll = 0
+}
+
+// previewLines returns the wrapped preview text for the highlighted item
+func (b *browser) previewLines(width int) []string {
+	item, ok := b.current()
+	if !ok {
+		return []string{"No matching sessions."}
+	}
+
+	text, ok := b.previewCache[item.ID]
+	if !ok {
+		var err error
+		text, err = b.opts.Preview(item)
+		if err != nil {
+			text = "Failed to load preview: " + err.Error()
+		}
+		b.previewCache[item.ID] = text
+	}
+
+	return wrapLines(text, width)
+}
+
+// bodyHeight is the number of rows between the header and the status line
+func bodyHeight(height int) int {
+	return max(height-2, 1)
+}
+
+func (b *browser) draw(t *terminal, width, height int) {
+	body := bodyHeight(height)
+	listWidth := max(min(width*2/5, 60), min(width, 24))
+	previewWidth := max(width-listWidth-1, 0)
+
+	// Keep the cursor row on screen
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+body {
+		b.offset = b.cursor - body + 1
+	}
+
+	preview := b.previewLines(previewWidth)
+	maxScroll := max(len(preview)-body, 0)
+	b.previewScroll = min(b.previewScroll, maxScroll)
+
+	var frame strings.Builder
+	frame.WriteString(cursorHome)
+
+	header := fmt.Sprintf(" %d/%d sessions", len(b.visible), len(b.opts.Items))
+	if len(b.selected) > 0 {
+		header += fmt.Sprintf(", %d selected", len(b.selected))
+	}
+	if b.filter != "" {
+		header += fmt.Sprintf("  filter: %s", b.filter)
+	}
+	frame.WriteString(styleBold + fit(header, width) + styleReset + clearLine + "\r\n")
+
+	for row := 0; row < body; row++ {
+		b.drawListRow(&frame, b.offset+row, listWidth)
+
+		if previewWidth > 0 {
+			frame.WriteString(styleDim + "│" + styleReset)
+			if line := b.previewScroll + row; line < len(preview) {
+				frame.WriteString(fit(preview[line], previewWidth))
+			}
+		}
+		frame.WriteString(clearLine + "\r\n")
+	}
+
+	switch {
+	case b.filtering:
+		frame.WriteString(fit(" /"+b.filter+"_", width))
+	case b.status != "":
+		frame.WriteString(fit(" "+b.status, width))
+	default:
+		frame.WriteString(styleDim + fit(" "+helpLine, width) + styleReset)
+	}
+	frame.WriteString(clearLine)
+
+	t.out.WriteString(frame.String())
+	t.out.Flush()
+}
+
+func (b *browser) drawListRow(frame *strings.Builder, position, width int) {
+	if position >= len(b.visible) {
+		frame.WriteString(strings.Repeat(" ", width))
+		return
+	}
+
+	item := b.opts.Items[b.visible[position]]
+
+	mark := "[ ] "
+	if b.selected[item.ID] {
+		mark = "[x] "
+	}
+
+	title := sanitizeLine(item.Title)
+	if title == "" {
+		title = "Untitled"
+	}
+	line := fit(mark+title+"  "+sanitizeLine(item.Detail), width)
+
+	switch {
+	case position == b.cursor && b.focus == focusList:
+		frame.WriteString(styleReverse + line + styleReset)
+	case position == b.cursor:
+		frame.WriteString(styleBold + line + styleReset)
+	default:
+		frame.WriteString(line)
+	}
+}
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// fuzzyScore reports whether every rune of pattern appears in text in order,
+// ignoring case, and scores the match so tighter matches rank first.
+// Consecutive runs and matches at word starts score higher; gaps cost a little.
+func fuzzyScore(pattern, text string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	patternRunes := []rune(pattern)
+	score := 0
+	pi := 0
+	lastMatch := -2
+	prev := ' '
+	position := 0
+
+	for _, r := range text {
+		if pi < len(patternRunes) && unicode.ToLower(r) == unicode.ToLower(patternRunes[pi]) {
+			switch {
+			case lastMatch == position-1:
+				score += 5
+			case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+				score += 3
+			default:
+				score++
+			}
+			if lastMatch >= 0 {
+				score -= min(position-lastMatch-1, 3)
+			}
+			lastMatch = position
+			pi++
+		}
+		prev = r
+		position++
+	}
+
+	if pi < len(patternRunes) {
+		return 0, false
+	}
+
+	// Prefer shorter titles when everything else is equal
+	score -= utf8.RuneCountInString(text) / 20
+
+	return score, true
+}
@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// key is a decoded keypress. Printable characters use keyRune with r set.
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyTab
+	keyBackspace
+	keyEscape
+	keyCtrlC
+	keyUnknown
+)
+
+const (
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	cursorHome     = "\x1b[H"
+	clearLine      = "\x1b[K"
+	styleReset     = "\x1b[0m"
+	styleReverse   = "\x1b[7m"
+	styleBold      = "\x1b[1m"
+	styleDim       = "\x1b[2m"
+)
+
+// terminal owns the raw-mode TTY for the lifetime of the browser
+type terminal struct {
+	in       *os.File
+	out      *bufio.Writer
+	reader   *bufio.Reader
+	oldState *term.State
+}
+
+func openTerminal(in *os.File, out io.Writer) (*terminal, error) {
+	if !term.IsTerminal(int(in.Fd())) {
+		return nil, fmt.Errorf("browse needs an interactive terminal")
+	}
+
+	oldState, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable raw mode: %w", err)
+	}
+
+	t := &terminal{
+		in:       in,
+		out:      bufio.NewWriter(out),
+		reader:   bufio.NewReader(in),
+		oldState: oldState,
+	}
+	t.out.WriteString(enterAltScreen + hideCursor)
+	t.out.Flush()
+
+	return t, nil
+}
+
+func (t *terminal) close() {
+	t.out.WriteString(styleReset + showCursor + exitAltScreen)
+	t.out.Flush()
+	term.Restore(int(t.in.Fd()), t.oldState)
+}
+
+// size returns the terminal dimensions, falling back to 80x24
+func (t *terminal) size() (int, int) {
+	width, height, err := term.GetSize(int(t.in.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+func (t *terminal) readKey() (key, error) {
+	r, _, err := t.reader.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch r {
+	case 3:
+		return key{kind: keyCtrlC}, nil
+	case '\r', '\n':
+		return key{kind: keyEnter}, nil
+	case '\t':
+		return key{kind: keyTab}, nil
+	case 127, 8:
+		return key{kind: keyBackspace}, nil
+	case 27:
+		return t.readEscape()
+	}
+
+	if unicode.IsPrint(r) {
+		return key{kind: keyRune, r: r}, nil
+	}
+	return key{kind: keyUnknown}, nil
+}
+
+// readEscape decodes the CSI sequences sent by arrow and paging keys. A lone
+// ESC (nothing buffered behind it) is the Escape key itself.
+func (t *terminal) readEscape() (key, error) {
+	if t.reader.Buffered() == 0 {
+		return key{kind: keyEscape}, nil
+	}
+
+	next, _, err := t.reader.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+	if next != '[' && next != 'O' {
+		return key{kind: keyEscape}, nil
+	}
+
+	var seq strings.Builder
+	for {
+		r, _, err := t.reader.ReadRune()
+		if err != nil {
+			return key{}, err
+		}
+		seq.WriteRune(r)
+		if r >= 0x40 && r <= 0x7e {
+			break
+		}
+	}
+
+	switch seq.String() {
+	case "A":
+		return key{kind: keyUp}, nil
+	case "B":
+		return key{kind: keyDown}, nil
+	case "5~":
+		return key{kind: keyPageUp}, nil
+	case "6~":
+		return key{kind: keyPageDown}, nil
+	case "H", "1~", "7~":
+		return key{kind: keyHome}, nil
+	case "F", "4~", "8~":
+		return key{kind: keyEnd}, nil
+	default:
+		return key{kind: keyUnknown}, nil
+	}
+}
+
+// fit truncates or pads s to exactly width terminal columns. Every rune is
+// assumed to be one column wide, which holds for the ASCII-heavy content of
+// transcripts; wide glyphs only shift the rest of their own line.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+
+	count := utf8.RuneCountInString(s)
+	if count <= width {
+		return s + strings.Repeat(" ", width-count)
+	}
+
+	runes := []rune(s)
+	if width == 1 {
+		return string(runes[:1])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// sanitizeLine replaces tabs and control characters so preview text cannot
+// move the cursor or change terminal state
+func sanitizeLine(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ReplaceAll(s, "\t", "    "))
+}
+
+// wrapLines splits text into display lines no wider than width
+func wrapLines(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(sanitizeLine(line))
+		if len(runes) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+
+	return lines
+}
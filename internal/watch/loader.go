@@ -0,0 +1,169 @@
+package watch
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// Loader reads sessions incrementally. It remembers every storage file it
+// decoded together with its modification time and size, and on the next load
+// only re-reads files that changed, appeared or disappeared.
+type Loader struct {
+	reader  *session.Reader
+	files   map[string]cachedFile
+	infos   map[string][]byte   // Last seen info file contents per session
+	digests map[string][32]byte // Last seen contents of sessions not kept in files
+}
+
+type cachedFile struct {
+	sessionID string
+	modTime   int64
+	size      int64
+	message   *session.Message
+	part      *session.MessagePart
+}
+
+// NewLoader creates a loader reading through reader
+func NewLoader(reader *session.Reader) *Loader {
+	return &Loader{
+		reader:  reader,
+		files:   make(map[string]cachedFile),
+		infos:   make(map[string][]byte),
+		digests: make(map[string][32]byte),
+	}
+}
+
+// Load returns the current state of a session and whether it differs from
+// the previous load. The result is assembled the same way as
+// session.Reader.ReadSession: messages by creation time, and each message's
+// parts by creation time.
+func (l *Loader) Load(sessionID string) (*session.Session, bool, error) {
+	info, err := l.reader.ReadSessionInfo(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	files, err := l.reader.SessionFiles(sessionID)
+	if errors.Is(err, session.ErrNotFileStorage) {
+		return l.loadRecords(sessionID)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	previous, loaded := l.infos[sessionID]
+	changed := !loaded || string(previous) != string(info.Raw)
+	l.infos[sessionID] = info.Raw
+
+	var messages []session.Message
+	partsByMessage := make(map[string][]session.MessagePart)
+	seen := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		if file.Kind == session.FileSessionInfo {
+			continue
+		}
+		seen[file.Path] = true
+
+		cached, reread, ok := l.load(file)
+		changed = changed || reread
+		if !ok {
+			continue // Skip files that are unreadable or mid-write
+		}
+
+		switch file.Kind {
+		case session.FileMessage:
+			messages = append(messages, *cached.message)
+		case session.FilePart:
+			partsByMessage[file.MessageID] = append(partsByMessage[file.MessageID], *cached.part)
+		}
+	}
+
+	// Forget files of this session that were deleted
+	for path, cached := range l.files {
+		if cached.sessionID == sessionID && !seen[path] {
+			delete(l.files, path)
+			changed = true
+		}
+	}
+
+	session.SortMessages(messages)
+
+	var parts []session.MessagePart
+	for _, message := range messages {
+		messageParts := partsByMessage[message.ID]
+		session.SortParts(messageParts)
+		parts = append(parts, messageParts...)
+	}
+
+	return &session.Session{
+		Info:     *info,
+		Messages: messages,
+		Parts:    parts,
+	}, changed, nil
+}
+
+// loadRecords reads a session kept in a database in full, since there are no
+// files to check, and compares it with the previous load
+func (l *Loader) loadRecords(sessionID string) (*session.Session, bool, error) {
+	sess, err := l.reader.ReadSession(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hash := sha256.New()
+	hash.Write(sess.Info.Raw)
+	for _, message := range sess.Messages {
+		hash.Write(message.Raw)
+	}
+	for _, part := range sess.Parts {
+		hash.Write(part.Raw)
+	}
+	var digest [32]byte
+	hash.Sum(digest[:0])
+
+	previous, loaded := l.digests[sessionID]
+	l.digests[sessionID] = digest
+
+	return sess, !loaded || previous != digest, nil
+}
+
+// load returns the decoded file, re-reading it only if it changed, and
+// reports whether it was re-read
+func (l *Loader) load(file session.SessionFile) (cachedFile, bool, bool) {
+	stat, err := os.Stat(file.Path)
+	if err != nil {
+		_, known := l.files[file.Path]
+		delete(l.files, file.Path)
+		return cachedFile{}, known, false
+	}
+
+	cached, ok := l.files[file.Path]
+	if ok && cached.modTime == stat.ModTime().UnixNano() && cached.size == stat.Size() {
+		return cached, false, true
+	}
+
+	cached = cachedFile{
+		sessionID: file.SessionID,
+		modTime:   stat.ModTime().UnixNano(),
+		size:      stat.Size(),
+	}
+	switch file.Kind {
+	case session.FileMessage:
+		cached.message, err = session.ReadMessageFile(file.Path)
+	case session.FilePart:
+		cached.part, err = session.ReadPartFile(file.Path)
+	}
+	if err != nil {
+		// Not cached, so the next load retries a file caught mid-write
+		_, known := l.files[file.Path]
+		delete(l.files, file.Path)
+		return cachedFile{}, known, false
+	}
+
+	l.files[file.Path] = cached
+	return cached, true, true
+}
@@ -0,0 +1,177 @@
+// Package watch keeps exports current while a session is in progress. It
+// watches a session's storage directories for filesystem notifications and
+// reloads the session, re-reading only the files that changed, once a burst
+// of writes has settled.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/fantomc0der/opencode-session-export/internal/session"
+)
+
+// DefaultDebounce is how long to wait after the last change before reloading.
+// opencode rewrites a part file many times while a reply streams in.
+const DefaultDebounce = 300 * time.Millisecond
+
+// maxDelayFactor bounds how long a steady stream of changes can postpone a
+// reload, as a multiple of the debounce interval
+const maxDelayFactor = 10
+
+// Options configures a watch
+type Options struct {
+	Debounce time.Duration
+	// Update is called once per session when the watch starts and again
+	// whenever the session changed
+	Update func(sess *session.Session) error
+	// Error receives failures that do not stop the watch, such as a session
+	// that could not be loaded or an output that could not be written
+	Error func(err error)
+}
+
+type watcher struct {
+	reader     *session.Reader
+	loader     *Loader
+	fsw        *fsnotify.Watcher
+	opts       Options
+	sessionIDs []string
+	// dirSessions maps each watched directory to the sessions stored in it.
+	// Some directories, such as the part root of the hash layout, are shared.
+	dirSessions map[string]map[string]bool
+}
+
+// Run watches the given sessions until ctx is cancelled
+func Run(ctx context.Context, reader *session.Reader, sessionIDs []string, opts Options) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.Error == nil {
+		opts.Error = func(error) {}
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	w := &watcher{
+		reader:      reader,
+		loader:      NewLoader(reader),
+		fsw:         fsw,
+		opts:        opts,
+		sessionIDs:  sessionIDs,
+		dirSessions: make(map[string]map[string]bool),
+	}
+
+	for _, sessionID := range sessionIDs {
+		w.refresh(sessionID)
+	}
+
+	timer := time.NewTimer(opts.Debounce)
+	timer.Stop()
+	pending := make(map[string]bool)
+	var firstChange time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			sessions := w.route(event)
+			if len(sessions) == 0 {
+				continue
+			}
+			if len(pending) == 0 {
+				firstChange = time.Now()
+			}
+			for sessionID := range sessions {
+				pending[sessionID] = true
+			}
+
+			// Wait for writes to settle, but not forever while a reply streams
+			deadline := firstChange.Add(maxDelayFactor * opts.Debounce)
+			timer.Reset(max(min(opts.Debounce, time.Until(deadline)), 0))
+
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			opts.Error(fmt.Errorf("file watcher: %w", err))
+
+		case <-timer.C:
+			// Refresh in the order sessions were given so output is stable
+			for _, sessionID := range w.sessionIDs {
+				if pending[sessionID] {
+					w.refresh(sessionID)
+				}
+			}
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// refresh reloads a session, reports it if it changed, and watches any
+// directories it gained, such as the part directory of a new message
+func (w *watcher) refresh(sessionID string) {
+	sess, changed, err := w.loader.Load(sessionID)
+	if err != nil {
+		w.opts.Error(fmt.Errorf("failed to load session %s: %w", sessionID, err))
+	} else if changed {
+		if err := w.opts.Update(sess); err != nil {
+			w.opts.Error(err)
+		}
+	}
+
+	dirs, err := w.reader.WatchDirs(sessionID)
+	if err != nil {
+		w.opts.Error(fmt.Errorf("failed to find directories of session %s: %w", sessionID, err))
+		return
+	}
+
+	watched := make(map[string]bool)
+	for _, dir := range w.fsw.WatchList() {
+		watched[dir] = true
+	}
+
+	for _, dir := range dirs {
+		if w.dirSessions[dir] == nil {
+			w.dirSessions[dir] = make(map[string]bool)
+		}
+		w.dirSessions[dir][sessionID] = true
+
+		// Watches vanish with their directory, so re-add rather than trust
+		// what was added before
+		if !watched[dir] {
+			if err := w.fsw.Add(dir); err != nil {
+				w.opts.Error(fmt.Errorf("failed to watch %s: %w", dir, err))
+			}
+		}
+	}
+}
+
+// route returns the sessions an event may affect
+func (w *watcher) route(event fsnotify.Event) map[string]bool {
+	if event.Op == fsnotify.Chmod {
+		return nil
+	}
+
+	sessions := make(map[string]bool)
+	for sessionID := range w.dirSessions[filepath.Dir(event.Name)] {
+		sessions[sessionID] = true
+	}
+	for sessionID := range w.dirSessions[event.Name] {
+		sessions[sessionID] = true
+	}
+
+	return sessions
+}